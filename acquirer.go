@@ -18,19 +18,295 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/goph/emperror"
 	"github.com/xmidt-org/bascule/acquire"
+	"github.com/xmidt-org/webpa-common/v2/logging"
 )
 
-// determineTokenAcquirer always returns a valid TokenAcquirer
-func determineTokenAcquirer(config WebhookConfig) (acquire.Acquirer, error) {
-	defaultAcquirer := &acquire.DefaultAcquirer{}
-	if config.JWT.AuthURL != "" && config.JWT.Buffer != 0 && config.JWT.Timeout != 0 {
-		return acquire.NewRemoteBearerTokenAcquirer(config.JWT)
+const (
+	acquirerNone   = "none"
+	acquirerBasic  = "basic"
+	acquirerJWT    = "jwt"
+	acquirerFile   = "file"
+	acquirerOAuth2 = "oauth2"
+
+	defaultOAuth2Timeout = 30 * time.Second
+	bearerTokenType      = "Bearer"
+)
+
+var (
+	errUnknownAcquirer = errors.New("unknown token acquirer type")
+	errNoBasicAuth     = errors.New("basic acquirer has no value")
+	errNoAuthURL       = errors.New("jwt acquirer has no auth url")
+	errNoJWTTimeout    = errors.New("jwt acquirer has no timeout")
+	errNoTokenFile     = errors.New("file acquirer has no path")
+	errEmptyTokenFile  = errors.New("token file is empty")
+	errNoTokenURL      = errors.New("oauth2 acquirer has no token url")
+	errNoClientID      = errors.New("oauth2 acquirer has no client id")
+	errNoClientSecret  = errors.New("oauth2 acquirer has no client secret")
+	errTokenRequest    = errors.New("token request failed")
+	errNoAccessToken   = errors.New("token response has no access token")
+)
+
+// AcquirerConfig chooses how the Authorization header sent when registering
+// webhooks is acquired.
+type AcquirerConfig struct {
+	// Type is none, basic, jwt, file, or oauth2.  If empty, it's jwt if
+	// webhook.jwt.authURL is set, basic if webhook.basic is set, and none if
+	// neither are.  If both are set, jwt is used.
+	Type string
+
+	File   FileAcquirerConfig
+	OAuth2 OAuth2AcquirerConfig
+}
+
+// FileAcquirerConfig reads the token from a file, such as a mounted secret.
+// The file is read again whenever it changes.
+type FileAcquirerConfig struct {
+	// Path is the file the token is read from.
+	Path string
+
+	// Prefix is put before the token, such as "Bearer".  If empty, the
+	// file holds the whole header value.
+	Prefix string
+}
+
+// OAuth2AcquirerConfig gets a bearer token with the OAuth2 client credentials
+// grant.
+type OAuth2AcquirerConfig struct {
+	// TokenURL is where the token is requested.
+	TokenURL string
+
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+
+	// Timeout is how long to wait for the token request.
+	Timeout time.Duration
+
+	// Buffer is how long before a token expires to get a new one.
+	Buffer time.Duration
+}
+
+// acquirerFactory creates a token acquirer, or returns an error if the config
+// for it is incomplete.
+type acquirerFactory func(WebhookConfig) (acquire.Acquirer, error)
+
+// acquirerFactories are the token acquirers that can be configured, by type.
+var acquirerFactories = map[string]acquirerFactory{
+	acquirerNone:   newNoneAcquirer,
+	acquirerBasic:  newBasicAcquirer,
+	acquirerJWT:    newJWTAcquirer,
+	acquirerFile:   newFileAcquirer,
+	acquirerOAuth2: newOAuth2Acquirer,
+}
+
+// determineTokenAcquirer creates the token acquirer of the configured type.
+func determineTokenAcquirer(config WebhookConfig, logger log.Logger) (acquire.Acquirer, error) {
+	acquirerType := strings.ToLower(config.Acquirer.Type)
+	if acquirerType == "" {
+		acquirerType = deriveAcquirerType(config, logger)
+	}
+	factory, ok := acquirerFactories[acquirerType]
+	if !ok {
+		return nil, emperror.With(errUnknownAcquirer, "type", config.Acquirer.Type)
 	}
+	acquirer, err := factory(config)
+	if err != nil {
+		return nil, emperror.WrapWith(err, "invalid token acquirer", "type", acquirerType)
+	}
+	return acquirer, nil
+}
 
-	if config.Basic != "" {
-		return acquire.NewFixedAuthAcquirer(config.Basic)
+// deriveAcquirerType gets the type from the basic and jwt config, for configs
+// written before the type could be chosen.  Like before, jwt takes precedence
+// over basic.
+func deriveAcquirerType(config WebhookConfig, logger log.Logger) string {
+	switch {
+	case config.JWT.AuthURL != "" && config.Basic != "":
+		logging.Warn(logger).Log(logging.MessageKey(), "Both basic and jwt are set, using jwt; choose one with acquirer.type")
+		return acquirerJWT
+	case config.JWT.AuthURL != "":
+		return acquirerJWT
+	case config.Basic != "":
+		return acquirerBasic
 	}
+	return acquirerNone
+}
+
+func newNoneAcquirer(WebhookConfig) (acquire.Acquirer, error) {
+	return &acquire.DefaultAcquirer{}, nil
+}
 
-	return defaultAcquirer, nil
+func newBasicAcquirer(config WebhookConfig) (acquire.Acquirer, error) {
+	if config.Basic == "" {
+		return nil, errNoBasicAuth
+	}
+	return acquire.NewFixedAuthAcquirer(config.Basic)
+}
+
+func newJWTAcquirer(config WebhookConfig) (acquire.Acquirer, error) {
+	switch {
+	case config.JWT.AuthURL == "":
+		return nil, errNoAuthURL
+	case config.JWT.Timeout <= 0:
+		return nil, errNoJWTTimeout
+	}
+	return acquire.NewRemoteBearerTokenAcquirer(config.JWT)
+}
+
+func newFileAcquirer(config WebhookConfig) (acquire.Acquirer, error) {
+	if config.Acquirer.File.Path == "" {
+		return nil, errNoTokenFile
+	}
+	f := &fileAcquirer{
+		path:   config.Acquirer.File.Path,
+		prefix: config.Acquirer.File.Prefix,
+	}
+	// read the file now, so a missing file is found at startup.
+	if _, err := f.Acquire(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// fileAcquirer gets the token from a file, reading it again when its size or
+// modification time changes.
+type fileAcquirer struct {
+	path   string
+	prefix string
+
+	lock    sync.Mutex
+	modTime time.Time
+	size    int64
+	token   string
+}
+
+func (f *fileAcquirer) Acquire() (string, error) {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return "", emperror.WrapWith(err, "failed to check token file", "path", f.path)
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.token != "" && info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return f.token, nil
+	}
+	data, err := ioutil.ReadFile(f.path)
+	if err != nil {
+		return "", emperror.WrapWith(err, "failed to read token file", "path", f.path)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", emperror.With(errEmptyTokenFile, "path", f.path)
+	}
+	if f.prefix != "" {
+		token = f.prefix + " " + token
+	}
+	f.token, f.modTime, f.size = token, info.ModTime(), info.Size()
+	return f.token, nil
+}
+
+func newOAuth2Acquirer(config WebhookConfig) (acquire.Acquirer, error) {
+	c := config.Acquirer.OAuth2
+	switch {
+	case c.TokenURL == "":
+		return nil, errNoTokenURL
+	case c.ClientID == "":
+		return nil, errNoClientID
+	case c.ClientSecret == "":
+		return nil, errNoClientSecret
+	}
+	if _, err := url.Parse(c.TokenURL); err != nil {
+		return nil, emperror.WrapWith(err, "failed to parse token url", "url", c.TokenURL)
+	}
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = defaultOAuth2Timeout
+	}
+	return &oauth2Acquirer{
+		config: c,
+		client: &http.Client{Timeout: timeout},
+		now:    time.Now,
+	}, nil
+}
+
+// oauth2Acquirer requests a token with the client credentials grant, keeping
+// it until it's about to expire.
+type oauth2Acquirer struct {
+	config OAuth2AcquirerConfig
+	client *http.Client
+	now    func() time.Time
+
+	lock       sync.Mutex
+	token      string
+	expiration time.Time
+}
+
+// oauth2Token is the token response from RFC 6749, section 5.1.
+type oauth2Token struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+func (o *oauth2Acquirer) Acquire() (string, error) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	if o.token != "" && o.now().Add(o.config.Buffer).Before(o.expiration) {
+		return o.token, nil
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(o.config.Scopes) > 0 {
+		form.Set("scope", strings.Join(o.config.Scopes, " "))
+	}
+	req, err := http.NewRequest(http.MethodPost, o.config.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", emperror.Wrap(err, "failed to create token request")
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(o.config.ClientID), url.QueryEscape(o.config.ClientSecret))
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return "", emperror.WrapWith(err, "failed to request token", "url", o.config.TokenURL)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		// read the body so the connection can be reused.
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		return "", emperror.With(errTokenRequest, "url", o.config.TokenURL, "code", resp.StatusCode)
+	}
+	var t oauth2Token
+	if err := json.NewDecoder(resp.Body).Decode(&t); err != nil {
+		return "", emperror.Wrap(err, "failed to decode token response")
+	}
+	if t.AccessToken == "" {
+		return "", errNoAccessToken
+	}
+
+	tokenType := bearerTokenType
+	if t.TokenType != "" && !strings.EqualFold(t.TokenType, bearerTokenType) {
+		tokenType = t.TokenType
+	}
+	token := tokenType + " " + t.AccessToken
+	// without an expiration, the token is requested every time.
+	o.token, o.expiration = "", time.Time{}
+	if t.ExpiresIn > 0 {
+		o.token, o.expiration = token, o.now().Add(time.Duration(t.ExpiresIn)*time.Second)
+	}
+	return token, nil
 }
@@ -18,11 +18,19 @@
 package main
 
 import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/xmidt-org/bascule/acquire"
+	"github.com/xmidt-org/webpa-common/v2/logging"
 )
 
 func TestDetermineTokenAcquirer(t *testing.T) {
@@ -51,6 +59,12 @@ func TestDetermineTokenAcquirer(t *testing.T) {
 			basicVal:              "test basic",
 			expectedTokenAcquirer: goodBasicAcquirer,
 		},
+		{
+			description: "Basic And JWT Uses JWT",
+			jwtConfig:   options,
+			basicVal:    "test basic",
+			expectJWT:   true,
+		},
 		{
 			description:           "Default Success",
 			expectedTokenAcquirer: defaultAcquirer,
@@ -64,7 +78,7 @@ func TestDetermineTokenAcquirer(t *testing.T) {
 				JWT:   tc.jwtConfig,
 				Basic: tc.basicVal,
 			}
-			tokenAcquirer, err := determineTokenAcquirer(config)
+			tokenAcquirer, err := determineTokenAcquirer(config, logging.NewTestLogger(nil, t))
 			assert.Nil(err)
 			if tc.expectJWT {
 				assert.NotEqual(goodBasicAcquirer, tokenAcquirer)
@@ -75,3 +89,181 @@ func TestDetermineTokenAcquirer(t *testing.T) {
 		})
 	}
 }
+
+func TestDetermineTokenAcquirerErrors(t *testing.T) {
+	tests := []struct {
+		description string
+		config      WebhookConfig
+		expectedErr error
+	}{
+		{
+			description: "Unknown Type",
+			config:      WebhookConfig{Acquirer: AcquirerConfig{Type: "magic"}},
+			expectedErr: errUnknownAcquirer,
+		},
+		{
+			description: "Partial JWT",
+			config:      WebhookConfig{JWT: acquire.RemoteBearerTokenAcquirerOptions{AuthURL: "/test"}},
+			expectedErr: errNoJWTTimeout,
+		},
+		{
+			description: "JWT Without URL",
+			config:      WebhookConfig{Acquirer: AcquirerConfig{Type: "jwt"}},
+			expectedErr: errNoAuthURL,
+		},
+		{
+			description: "Basic Without Value",
+			config:      WebhookConfig{Acquirer: AcquirerConfig{Type: "basic"}},
+			expectedErr: errNoBasicAuth,
+		},
+		{
+			description: "File Without Path",
+			config:      WebhookConfig{Acquirer: AcquirerConfig{Type: "file"}},
+			expectedErr: errNoTokenFile,
+		},
+		{
+			description: "Missing File",
+			config: WebhookConfig{Acquirer: AcquirerConfig{
+				Type: "file",
+				File: FileAcquirerConfig{Path: filepath.Join(os.TempDir(), "svalinn-missing-token")},
+			}},
+			expectedErr: errors.New("failed to check token file"),
+		},
+		{
+			description: "OAuth2 Without Token URL",
+			config:      WebhookConfig{Acquirer: AcquirerConfig{Type: "oauth2"}},
+			expectedErr: errNoTokenURL,
+		},
+		{
+			description: "OAuth2 Without Client ID",
+			config: WebhookConfig{Acquirer: AcquirerConfig{
+				Type:   "OAuth2",
+				OAuth2: OAuth2AcquirerConfig{TokenURL: "http://auth.example.com/token"},
+			}},
+			expectedErr: errNoClientID,
+		},
+		{
+			description: "OAuth2 Without Client Secret",
+			config: WebhookConfig{Acquirer: AcquirerConfig{
+				Type:   "oauth2",
+				OAuth2: OAuth2AcquirerConfig{TokenURL: "http://auth.example.com/token", ClientID: "svalinn"},
+			}},
+			expectedErr: errNoClientSecret,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			acquirer, err := determineTokenAcquirer(tc.config, logging.NewTestLogger(nil, t))
+			assert.Nil(t, acquirer)
+			require.NotNil(t, err)
+			assert.Contains(t, err.Error(), tc.expectedErr.Error())
+		})
+	}
+}
+
+func TestFileAcquirer(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "svalinn-token")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "token")
+	require.Nil(t, ioutil.WriteFile(path, []byte("first\n"), 0600))
+
+	acquirer, err := determineTokenAcquirer(WebhookConfig{Acquirer: AcquirerConfig{
+		Type: "file",
+		File: FileAcquirerConfig{Path: path, Prefix: "Bearer"},
+	}}, logging.NewTestLogger(nil, t))
+	require.Nil(t, err)
+	token, err := acquirer.Acquire()
+	assert.Nil(err)
+	assert.Equal("Bearer first", token)
+
+	require.Nil(t, ioutil.WriteFile(path, []byte("second"), 0600))
+	later := time.Now().Add(time.Minute)
+	require.Nil(t, os.Chtimes(path, later, later))
+	token, err = acquirer.Acquire()
+	assert.Nil(err)
+	assert.Equal("Bearer second", token)
+
+	require.Nil(t, ioutil.WriteFile(path, []byte(" "), 0600))
+	_, err = acquirer.Acquire()
+	require.NotNil(t, err)
+	assert.Contains(err.Error(), errEmptyTokenFile.Error())
+}
+
+func TestOAuth2Acquirer(t *testing.T) {
+	var (
+		requests  int
+		expiresIn = `3600`
+		code      = http.StatusOK
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "svalinn" || secret != "shh" || r.FormValue("grant_type") != "client_credentials" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.FormValue("scope") != "webhook:write events" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(code)
+		w.Write([]byte(`{"access_token":"abc","token_type":"bearer","expires_in":` + expiresIn + `}`))
+	}))
+	defer server.Close()
+
+	newAcquirer := func(t *testing.T) *oauth2Acquirer {
+		acquirer, err := determineTokenAcquirer(WebhookConfig{Acquirer: AcquirerConfig{
+			Type: "oauth2",
+			OAuth2: OAuth2AcquirerConfig{
+				TokenURL:     server.URL,
+				ClientID:     "svalinn",
+				ClientSecret: "shh",
+				Scopes:       []string{"webhook:write", "events"},
+				Buffer:       time.Minute,
+			},
+		}}, logging.NewTestLogger(nil, t))
+		require.Nil(t, err)
+		return acquirer.(*oauth2Acquirer)
+	}
+
+	t.Run("Token Kept Until Expiring", func(t *testing.T) {
+		assert := assert.New(t)
+		requests, expiresIn, code = 0, `3600`, http.StatusOK
+		acquirer := newAcquirer(t)
+		now := time.Now()
+		acquirer.now = func() time.Time { return now }
+		for i := 0; i < 2; i++ {
+			token, err := acquirer.Acquire()
+			assert.Nil(err)
+			assert.Equal("Bearer abc", token)
+		}
+		assert.Equal(1, requests)
+
+		// within the buffer of the expiration, a new token is requested.
+		now = now.Add(time.Hour - 30*time.Second)
+		_, err := acquirer.Acquire()
+		assert.Nil(err)
+		assert.Equal(2, requests)
+	})
+
+	t.Run("No Expiration", func(t *testing.T) {
+		assert := assert.New(t)
+		requests, expiresIn, code = 0, `0`, http.StatusOK
+		acquirer := newAcquirer(t)
+		for i := 0; i < 2; i++ {
+			_, err := acquirer.Acquire()
+			assert.Nil(err)
+		}
+		assert.Equal(2, requests)
+	})
+
+	t.Run("Request Failed", func(t *testing.T) {
+		requests, expiresIn, code = 0, `3600`, http.StatusInternalServerError
+		_, err := newAcquirer(t).Acquire()
+		require.NotNil(t, err)
+		assert.Contains(t, err.Error(), errTokenRequest.Error())
+	})
+}
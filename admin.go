@@ -0,0 +1,159 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-kit/kit/log"
+	"github.com/gorilla/mux"
+	"github.com/xmidt-org/webpa-common/v2/logging"
+	webhook "github.com/xmidt-org/wrp-listener"
+)
+
+const (
+	redactedSecret = "<redacted>"
+)
+
+// AdminConfig sets up the admin endpoints, which report the webhook
+// registrations and can register them on demand, along with the records of
+// the in-memory database if it's used.  They should only be reachable by
+// operators.
+type AdminConfig struct {
+	// Address is where the admin server listens.  If empty, there is no admin
+	// server.
+	Address string
+}
+
+// registrationReport is what the admin endpoints report about a registration.
+type registrationReport struct {
+	Name    string             `json:"name"`
+	Request webhook.W          `json:"request"`
+	Status  registrationStatus `json:"status"`
+}
+
+type adminHandler struct {
+	registrations registrationSupervisors
+	records       *memoryDb
+	logger        log.Logger
+}
+
+// newAdminHandler creates the router for the admin endpoints:
+//
+//	GET    /registrations                 reports every registration
+//	GET    /registrations/{name}          reports one registration
+//	POST   /registrations/{name}/register registers the webhook now
+//
+// With the in-memory database, it also serves its records:
+//
+//	GET    /records?device={id}&limit={n} reports the newest records
+//	DELETE /records                       removes every record
+func newAdminHandler(registrations registrationSupervisors, records *memoryDb, logger log.Logger) http.Handler {
+	a := &adminHandler{registrations: registrations, records: records, logger: logger}
+	router := mux.NewRouter()
+	router.HandleFunc(apiBase+"/registrations", a.list).Methods(http.MethodGet)
+	router.HandleFunc(apiBase+"/registrations/{name}", a.get).Methods(http.MethodGet)
+	router.HandleFunc(apiBase+"/registrations/{name}/register", a.registerNow).Methods(http.MethodPost)
+	if records != nil {
+		router.HandleFunc(apiBase+"/records", a.getRecords).Methods(http.MethodGet)
+		router.HandleFunc(apiBase+"/records", a.clearRecords).Methods(http.MethodDelete)
+	}
+	return router
+}
+
+func (a *adminHandler) list(writer http.ResponseWriter, _ *http.Request) {
+	reports := make([]registrationReport, 0, len(a.registrations))
+	for _, r := range a.registrations {
+		reports = append(reports, report(r))
+	}
+	a.writeJSON(writer, reports)
+}
+
+func (a *adminHandler) get(writer http.ResponseWriter, req *http.Request) {
+	r := a.find(mux.Vars(req)["name"])
+	if r == nil {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+	a.writeJSON(writer, report(r))
+}
+
+func (a *adminHandler) registerNow(writer http.ResponseWriter, req *http.Request) {
+	r := a.find(mux.Vars(req)["name"])
+	if r == nil {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+	logging.Info(a.logger).Log(logging.MessageKey(), "Registering webhook on request", "registration", r.name)
+	r.RegisterNow()
+	writer.WriteHeader(http.StatusAccepted)
+}
+
+func (a *adminHandler) getRecords(writer http.ResponseWriter, req *http.Request) {
+	limit := 0
+	if l := req.URL.Query().Get("limit"); l != "" {
+		var err error
+		limit, err = strconv.Atoi(l)
+		if err != nil || limit < 0 {
+			writer.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	a.writeJSON(writer, a.records.getRecords(req.URL.Query().Get("device"), limit))
+}
+
+func (a *adminHandler) clearRecords(writer http.ResponseWriter, _ *http.Request) {
+	logging.Info(a.logger).Log(logging.MessageKey(), "Removing the in-memory database's records on request")
+	a.records.clear()
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (a *adminHandler) find(name string) *registrationSupervisor {
+	for _, r := range a.registrations {
+		if r.name == name {
+			return r
+		}
+	}
+	return nil
+}
+
+func (a *adminHandler) writeJSON(writer http.ResponseWriter, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		logging.Error(a.logger).Log(logging.MessageKey(), "Failed to marshal admin response", logging.ErrorKey(), err.Error())
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.Write(body)
+}
+
+// report gets the registration's request, without its secret, and status.
+func report(r *registrationSupervisor) registrationReport {
+	request := r.request
+	if request.Config.Secret != "" {
+		request.Config.Secret = redactedSecret
+	}
+	return registrationReport{
+		Name:    r.name,
+		Request: request,
+		Status:  r.getStatus(),
+	}
+}
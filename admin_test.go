@@ -0,0 +1,188 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/metrics/provider"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/codex-db"
+	"github.com/xmidt-org/webpa-common/v2/logging"
+	webhook "github.com/xmidt-org/wrp-listener"
+	"github.com/xmidt-org/wrp-listener/webhookClient"
+)
+
+func TestAdminHandler(t *testing.T) {
+	newSupervisor := func(name string, secret string, registerer *mockRegisterer) *registrationSupervisor {
+		request := webhook.W{Events: []string{".*"}}
+		request.Config.URL = "http://svalinn/api/v1/" + name
+		request.Config.Secret = secret
+		r, err := newRegistrationSupervisor(supervisorConfig{
+			name:       name,
+			request:    request,
+			registerer: registerer,
+			interval:   time.Hour,
+			measures:   webhookClient.NewMeasures(provider.NewDiscardProvider()),
+			logger:     logging.NewTestLogger(nil, t),
+		})
+		require.Nil(t, err)
+		return r
+	}
+	failing := new(mockRegisterer)
+	failing.On("Register").Return(errors.New("test register error")).Once()
+	registrations := registrationSupervisors{
+		newSupervisor("default", "super secret", new(mockRegisterer)),
+		newSupervisor("reboots", "", failing),
+	}
+	registrations[1].register()
+	handler := newAdminHandler(registrations, nil, logging.NewTestLogger(nil, t))
+
+	serve := func(method string, path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(method, path, nil))
+		return rr
+	}
+
+	t.Run("List", func(t *testing.T) {
+		assert := assert.New(t)
+		rr := serve(http.MethodGet, apiBase+"/registrations")
+		require.Equal(t, http.StatusOK, rr.Code)
+		var reports []registrationReport
+		require.Nil(t, json.Unmarshal(rr.Body.Bytes(), &reports))
+		require.Len(t, reports, 2)
+		assert.Equal("default", reports[0].Name)
+		assert.Equal(redactedSecret, reports[0].Request.Config.Secret)
+		assert.NotContains(rr.Body.String(), "super secret")
+		assert.Equal("reboots", reports[1].Name)
+		assert.Empty(reports[1].Request.Config.Secret)
+		assert.Equal("test register error", reports[1].Status.LastError)
+		assert.Equal(1, reports[1].Status.ConsecutiveFailures)
+	})
+
+	t.Run("Get", func(t *testing.T) {
+		assert := assert.New(t)
+		rr := serve(http.MethodGet, apiBase+"/registrations/reboots")
+		require.Equal(t, http.StatusOK, rr.Code)
+		var report registrationReport
+		require.Nil(t, json.Unmarshal(rr.Body.Bytes(), &report))
+		assert.Equal("reboots", report.Name)
+		assert.Equal("http://svalinn/api/v1/reboots", report.Request.Config.URL)
+		assert.False(report.Status.LastAttempt.IsZero())
+	})
+
+	t.Run("Get Unknown", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, apiBase+"/registrations/nope").Code)
+	})
+
+	t.Run("Register Now", func(t *testing.T) {
+		assert := assert.New(t)
+		rr := serve(http.MethodPost, apiBase+"/registrations/default/register")
+		assert.Equal(http.StatusAccepted, rr.Code)
+		select {
+		case <-registrations[0].trigger:
+		default:
+			t.Error("registration wasn't triggered")
+		}
+	})
+
+	t.Run("Register Unknown", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, serve(http.MethodPost, apiBase+"/registrations/nope/register").Code)
+	})
+
+	t.Run("Register Wrong Method", func(t *testing.T) {
+		assert.Equal(t, http.StatusMethodNotAllowed, serve(http.MethodGet, apiBase+"/registrations/default/register").Code)
+	})
+
+	t.Run("No Records", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, apiBase+"/records").Code)
+	})
+}
+
+func TestAdminHandlerRecords(t *testing.T) {
+	records := newMemoryDb(MemoryDbConfig{})
+	require.Nil(t, records.InsertRecords(
+		db.Record{DeviceID: "mac:112233445566", BirthDate: 1},
+		db.Record{DeviceID: "mac:aabbccddeeff", BirthDate: 2},
+		db.Record{DeviceID: "mac:112233445566", BirthDate: 3},
+	))
+	handler := newAdminHandler(nil, records, logging.NewTestLogger(nil, t))
+	serve := func(method string, path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(method, path, nil))
+		return rr
+	}
+
+	tests := []struct {
+		description        string
+		path               string
+		expectedStatus     int
+		expectedBirthDates []int64
+	}{
+		{
+			description:        "All",
+			path:               apiBase + "/records",
+			expectedStatus:     http.StatusOK,
+			expectedBirthDates: []int64{1, 2, 3},
+		},
+		{
+			description:        "Device",
+			path:               apiBase + "/records?device=mac:112233445566",
+			expectedStatus:     http.StatusOK,
+			expectedBirthDates: []int64{1, 3},
+		},
+		{
+			description:        "Limit",
+			path:               apiBase + "/records?device=mac:112233445566&limit=1",
+			expectedStatus:     http.StatusOK,
+			expectedBirthDates: []int64{3},
+		},
+		{
+			description:    "Bad Limit",
+			path:           apiBase + "/records?limit=some",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			rr := serve(http.MethodGet, tc.path)
+			require.Equal(t, tc.expectedStatus, rr.Code)
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+			var got []db.Record
+			require.Nil(t, json.Unmarshal(rr.Body.Bytes(), &got))
+			birthDates := make([]int64, 0, len(got))
+			for _, r := range got {
+				birthDates = append(birthDates, r.BirthDate)
+			}
+			assert.Equal(t, tc.expectedBirthDates, birthDates)
+		})
+	}
+
+	t.Run("Clear", func(t *testing.T) {
+		assert.Equal(t, http.StatusNoContent, serve(http.MethodDelete, apiBase+"/records").Code)
+		assert.Equal(t, "[]", serve(http.MethodGet, apiBase+"/records").Body.String())
+	})
+}
@@ -0,0 +1,264 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-kit/kit/log"
+	"github.com/goph/emperror"
+	"github.com/justinas/alice"
+	"github.com/xmidt-org/bascule"
+	"github.com/xmidt-org/bascule/basculehttp"
+	"github.com/xmidt-org/webpa-common/v2/basculemetrics"
+	"github.com/xmidt-org/webpa-common/v2/logging"
+)
+
+const (
+	authModeNone      = "none"
+	authModeSignature = "signature"
+	authModeJWT       = "jwt"
+	authModeMTLS      = "mtls"
+
+	noneTokenType = "none"
+	mtlsTokenType = "mtls"
+)
+
+var (
+	errUnknownAuthMode = errors.New("unknown auth mode")
+	errNoneCombined    = errors.New("auth mode none can't be combined with other modes")
+	errNoneNotAllowed  = errors.New("auth mode is none but auth.allowNone isn't set")
+	errSignatureConfig = errors.New("auth mode signature needs secret.header and webhook.request.config.secret")
+	errJWTConfig       = errors.New("auth mode jwt needs jwt.jwks")
+	errMTLSConfig      = errors.New("auth mode mtls needs tls.address and tls.clientCACertFile")
+)
+
+// AuthConfig chooses how requests to the webhook endpoint are authenticated.
+type AuthConfig struct {
+	// Mode is none, signature, jwt, or mtls.  Modes can be combined with a
+	// "+", like "signature+jwt".  With both signature and jwt, a request can
+	// use either one; mtls is required on top of the others.  If empty, the
+	// mode is worked out from which of secret and jwt are configured.
+	Mode string
+
+	// AllowNone must be set for Svalinn to start without authentication.
+	AllowNone bool
+}
+
+// authMode is the set of ways requests are authenticated.  No modes means
+// none.
+type authMode struct {
+	signature bool
+	jwt       bool
+	mtls      bool
+}
+
+func parseAuthMode(mode string) (authMode, error) {
+	var (
+		m    authMode
+		none bool
+	)
+	for _, s := range strings.FieldsFunc(strings.ToLower(mode), func(r rune) bool { return r == '+' || r == ',' }) {
+		switch strings.TrimSpace(s) {
+		case authModeNone:
+			none = true
+		case authModeSignature:
+			m.signature = true
+		case authModeJWT:
+			m.jwt = true
+		case authModeMTLS:
+			m.mtls = true
+		default:
+			return authMode{}, emperror.With(errUnknownAuthMode, "mode", s)
+		}
+	}
+	if none && !m.isNone() {
+		return authMode{}, errNoneCombined
+	}
+	return m, nil
+}
+
+// deriveAuthMode works out the mode from the config, for configs written
+// before the mode could be set.
+func deriveAuthMode(config *SvalinnConfig) authMode {
+	return authMode{
+		signature: config.Secret.Header != "" && config.Webhook.Request.Config.Secret != "",
+		jwt:       config.JWT.JWKS != "",
+	}
+}
+
+func (m authMode) isNone() bool {
+	return !m.signature && !m.jwt && !m.mtls
+}
+
+// withoutSignature gets the mode with signature validation turned off, for
+// calls that have no body to check a signature against.
+func (m authMode) withoutSignature() authMode {
+	m.signature = false
+	return m
+}
+
+func (m authMode) String() string {
+	if m.isNone() {
+		return authModeNone
+	}
+	var modes []string
+	if m.signature {
+		modes = append(modes, authModeSignature)
+	}
+	if m.jwt {
+		modes = append(modes, authModeJWT)
+	}
+	if m.mtls {
+		modes = append(modes, authModeMTLS)
+	}
+	return strings.Join(modes, "+")
+}
+
+// validate makes sure everything each mode needs is configured.
+func (m authMode) validate(config *SvalinnConfig) error {
+	if m.isNone() && !config.Auth.AllowNone {
+		return errNoneNotAllowed
+	}
+	if m.signature && (config.Secret.Header == "" || config.Webhook.Request.Config.Secret == "") {
+		return errSignatureConfig
+	}
+	for _, r := range config.Webhook.Registrations {
+		if m.signature && r.Request.Config.Secret == "" {
+			return emperror.With(errSignatureConfig, "registration", r.Name)
+		}
+	}
+	if m.jwt && config.JWT.JWKS == "" {
+		return errJWTConfig
+	}
+	if m.mtls && (config.TLS.Address == "" || config.TLS.ClientCACertFile == "") {
+		return errMTLSConfig
+	}
+	return nil
+}
+
+// newAuthChain builds the middleware that authenticates requests to an
+// endpoint, with signatures made using the endpoint's secret.  The chain
+// always sets the logger, authenticates with the modes given, and reports the
+// outcome to the listener.
+func newAuthChain(m authMode, config *SvalinnConfig, secret string, rotation SecretRotationConfig, measures *Measures, listener *basculemetrics.MetricListener, logger log.Logger) (alice.Chain, error) {
+	chain := alice.New(SetLogger(logger))
+	if m.mtls {
+		chain = chain.Append(requireClientCertificate(listener.OnErrorResponse))
+	}
+
+	var signatureChain, jwtChain alice.Chain
+	if m.signature {
+		constructor, err := newSignatureConstructor(config.Secret, secret, rotation, measures,
+			basculehttp.WithCLogger(GetLogger),
+			basculehttp.WithCErrorResponseFunc(listener.OnErrorResponse),
+		)
+		if err != nil {
+			return alice.Chain{}, emperror.Wrap(err, "failed to create signature validation")
+		}
+		signatureChain = alice.New(constructor)
+	}
+	if m.jwt {
+		var err error
+		jwtChain, err = newJWTConstructor(config.JWT,
+			[]basculehttp.COption{basculehttp.WithCLogger(GetLogger), basculehttp.WithCErrorResponseFunc(listener.OnErrorResponse)},
+			[]basculehttp.EOption{basculehttp.WithELogger(GetLogger), basculehttp.WithEErrorResponseFunc(listener.OnErrorResponse)},
+		)
+		if err != nil {
+			return alice.Chain{}, emperror.Wrap(err, "failed to create JWT validation")
+		}
+	}
+
+	switch {
+	case m.signature && m.jwt:
+		chain = chain.Append(selectAuth(jwtChain, signatureChain))
+	case m.signature:
+		chain = chain.Extend(signatureChain)
+	case m.jwt:
+		chain = chain.Extend(jwtChain)
+	case m.isNone():
+		chain = chain.Append(anonymous)
+	}
+	return chain.Append(basculehttp.NewListenerDecorator(listener)), nil
+}
+
+// logAuthMode logs the mode and the settings that go with it.
+func logAuthMode(logger log.Logger, m authMode, config *SvalinnConfig) {
+	keyvals := []interface{}{logging.MessageKey(), "Authenticating requests", "mode", m.String()}
+	if m.signature {
+		// the registration only carries the secret, so nothing makes the
+		// sender sign with the algorithm svalinn expects.
+		expected, err := signatureHeader(config.Secret)
+		if err != nil {
+			expected = err.Error()
+		}
+		if alg, err := getHashAlgorithm(config.Secret.Algorithm); err == nil && alg.key != hashAlgorithms[defaultSignatureAlgorithm].key {
+			logging.Warn(logger).Log(logging.MessageKey(), "Signature algorithm isn't sha1, the sender must be configured to sign the same way or every event is rejected",
+				"expectedHeader", expected)
+		}
+		keyvals = append(keyvals, "expectedHeader", expected, "replayProtection", config.Secret.Replay.Enabled)
+	}
+	if m.jwt {
+		keyvals = append(keyvals, "jwks", config.JWT.JWKS)
+	}
+	if m.mtls {
+		keyvals = append(keyvals, "allowedSubjects", config.TLS.AllowedSubjects)
+	}
+	if m.isNone() {
+		logging.Warn(logger).Log(keyvals...)
+		return
+	}
+	logging.Info(logger).Log(keyvals...)
+}
+
+// requireClientCertificate only lets through requests made over TLS with a
+// verified client certificate.  The certificate's subject is the token.
+func requireClientCertificate(onError basculehttp.OnErrorResponse) alice.Constructor {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+				logging.Error(GetLogger(r.Context())).Log(logging.MessageKey(), "Rejected request without a client certificate")
+				onError(basculehttp.MissingAuthentication, errNoClientCertChains)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			subject := r.TLS.VerifiedChains[0][0].Subject
+			ctx := bascule.WithAuthentication(r.Context(), bascule.Authentication{
+				Authorization: mtlsTokenType,
+				Token:         bascule.NewToken(mtlsTokenType, subject.CommonName, bascule.NewAttributes(map[string]interface{}{"subject": subject.String()})),
+				Request:       bascule.Request{URL: r.URL, Method: r.Method},
+			})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// anonymous marks requests as authenticated when no authentication is
+// configured, so the rest of the chain is the same as for the other modes.
+func anonymous(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := bascule.WithAuthentication(r.Context(), bascule.Authentication{
+			Authorization: noneTokenType,
+			Token:         bascule.NewToken(noneTokenType, "", bascule.NewAttributes(map[string]interface{}{})),
+			Request:       bascule.Request{URL: r.URL, Method: r.Method},
+		})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,238 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/metrics/provider"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/bascule"
+	"github.com/xmidt-org/webpa-common/v2/basculemetrics"
+	"github.com/xmidt-org/webpa-common/v2/logging"
+	webhook "github.com/xmidt-org/wrp-listener"
+)
+
+func TestParseAuthMode(t *testing.T) {
+	tests := []struct {
+		mode         string
+		expectedMode authMode
+		expectedStr  string
+		expectedErr  error
+	}{
+		{mode: "none", expectedStr: "none"},
+		{mode: "signature", expectedMode: authMode{signature: true}, expectedStr: "signature"},
+		{mode: "JWT", expectedMode: authMode{jwt: true}, expectedStr: "jwt"},
+		{mode: "mtls+signature", expectedMode: authMode{signature: true, mtls: true}, expectedStr: "signature+mtls"},
+		{mode: "signature, jwt", expectedMode: authMode{signature: true, jwt: true}, expectedStr: "signature+jwt"},
+		{mode: "none+jwt", expectedErr: errNoneCombined},
+		{mode: "basic", expectedErr: errUnknownAuthMode},
+	}
+	for _, tc := range tests {
+		t.Run(tc.mode, func(t *testing.T) {
+			assert := assert.New(t)
+			mode, err := parseAuthMode(tc.mode)
+			if tc.expectedErr != nil {
+				require.NotNil(t, err)
+				assert.Contains(err.Error(), tc.expectedErr.Error())
+				return
+			}
+			assert.Nil(err)
+			assert.Equal(tc.expectedMode, mode)
+			assert.Equal(tc.expectedStr, mode.String())
+		})
+	}
+}
+
+func TestAuthModeValidate(t *testing.T) {
+	signatureConfig := func() *SvalinnConfig {
+		c := &SvalinnConfig{Secret: SecretConfig{Header: "X-Webpa-Signature"}}
+		c.Webhook.Request.Config.Secret = "secret"
+		return c
+	}
+	tests := []struct {
+		description string
+		mode        authMode
+		config      *SvalinnConfig
+		expectedErr error
+	}{
+		{
+			description: "None Allowed",
+			config:      &SvalinnConfig{Auth: AuthConfig{AllowNone: true}},
+		},
+		{
+			description: "None Not Allowed",
+			config:      &SvalinnConfig{},
+			expectedErr: errNoneNotAllowed,
+		},
+		{
+			description: "Signature",
+			mode:        authMode{signature: true},
+			config:      signatureConfig(),
+		},
+		{
+			description: "Signature Without Secret",
+			mode:        authMode{signature: true},
+			config:      &SvalinnConfig{Secret: SecretConfig{Header: "X-Webpa-Signature"}},
+			expectedErr: errSignatureConfig,
+		},
+		{
+			description: "Signature Without Registration Secret",
+			mode:        authMode{signature: true},
+			config: func() *SvalinnConfig {
+				c := signatureConfig()
+				c.Webhook.Registrations = []RegistrationConfig{{Name: "other"}}
+				return c
+			}(),
+			expectedErr: errSignatureConfig,
+		},
+		{
+			description: "JWT Without JWKS",
+			mode:        authMode{jwt: true},
+			config:      &SvalinnConfig{},
+			expectedErr: errJWTConfig,
+		},
+		{
+			description: "mTLS",
+			mode:        authMode{mtls: true},
+			config:      &SvalinnConfig{TLS: TLSConfig{Address: ":8443", ClientCACertFile: "ca.pem"}},
+		},
+		{
+			description: "mTLS Without Client CA",
+			mode:        authMode{mtls: true},
+			config:      &SvalinnConfig{TLS: TLSConfig{Address: ":8443"}},
+			expectedErr: errMTLSConfig,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			err := tc.mode.validate(tc.config)
+			if tc.expectedErr == nil {
+				assert.Nil(t, err)
+				return
+			}
+			require.NotNil(t, err)
+			assert.Contains(t, err.Error(), tc.expectedErr.Error())
+		})
+	}
+}
+
+func TestNewAuthChain(t *testing.T) {
+	const secret = "secret"
+	body := "test body"
+	h := hmac.New(sha1.New, []byte(secret))
+	h.Write([]byte(body))
+	signature := "Sha1=" + hex.EncodeToString(h.Sum(nil))
+
+	clientCert := &x509.Certificate{Subject: pkix.Name{CommonName: "sender"}}
+	verified := &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{clientCert}}}
+
+	tests := []struct {
+		description       string
+		mode              authMode
+		signature         string
+		tls               *tls.ConnectionState
+		expectedCode      int
+		expectedTokenType string
+	}{
+		{
+			description:       "None",
+			expectedCode:      http.StatusOK,
+			expectedTokenType: noneTokenType,
+		},
+		{
+			description:       "Signature",
+			mode:              authMode{signature: true},
+			signature:         signature,
+			expectedCode:      http.StatusOK,
+			expectedTokenType: "Sha1",
+		},
+		{
+			description:  "Missing Signature",
+			mode:         authMode{signature: true},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			description:       "mTLS",
+			mode:              authMode{mtls: true},
+			tls:               verified,
+			expectedCode:      http.StatusOK,
+			expectedTokenType: mtlsTokenType,
+		},
+		{
+			description:  "mTLS Without Certificate",
+			mode:         authMode{mtls: true},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			description:       "mTLS And Signature",
+			mode:              authMode{signature: true, mtls: true},
+			signature:         signature,
+			tls:               verified,
+			expectedCode:      http.StatusOK,
+			expectedTokenType: "Sha1",
+		},
+		{
+			description:  "mTLS And Signature Without Certificate",
+			mode:         authMode{signature: true, mtls: true},
+			signature:    signature,
+			expectedCode: http.StatusUnauthorized,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			config := &SvalinnConfig{
+				Secret: SecretConfig{Header: "X-Webpa-Signature", Delimiter: "=", Algorithm: defaultSignatureAlgorithm},
+				Webhook: WebhookConfig{Request: webhook.W{
+					Config: webhook.Config{Secret: secret},
+				}},
+			}
+			chain, err := newAuthChain(tc.mode, config, secret, SecretRotationConfig{}, NewMeasures(provider.NewDiscardProvider()),
+				basculemetrics.NewMetricListener(nil), logging.NewTestLogger(nil, t))
+			require.Nil(err)
+
+			var tokenType string
+			handler := chain.Then(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if auth, ok := bascule.FromContext(r.Context()); ok {
+					tokenType = auth.Token.Type()
+				}
+			}))
+			request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+			if tc.signature != "" {
+				request.Header.Set("X-Webpa-Signature", tc.signature)
+			}
+			request.TLS = tc.tls
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, request)
+			assert.Equal(tc.expectedCode, rr.Code)
+			assert.Equal(tc.expectedTokenType, tokenType)
+		})
+	}
+}
@@ -0,0 +1,75 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	defaultMaxDecompressedSize = 16 * 1024 * 1024
+)
+
+var (
+	errUnsupportedEncoding = errors.New("unsupported content encoding")
+)
+
+// decompressBody returns a reader of the body decompressed according to the
+// Content-Encoding header.  maxSize is the most memory the zstd decoder may
+// use, so a small body can't make it allocate a huge window.
+func decompressBody(body io.Reader, encoding string, maxSize int64) (io.ReadCloser, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "", "identity":
+		return ioutil.NopCloser(body), nil
+	case "gzip", "x-gzip":
+		return gzip.NewReader(body)
+	case "zstd":
+		decoder, err := zstd.NewReader(body, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxMemory(uint64(maxSize)))
+		if err != nil {
+			return nil, err
+		}
+		return &zstdBody{decoder: decoder}, nil
+	}
+	return nil, errUnsupportedEncoding
+}
+
+// zstdBody reports the zstd decoder running into its memory limit as the
+// request being too large.
+type zstdBody struct {
+	decoder *zstd.Decoder
+}
+
+func (z *zstdBody) Read(p []byte) (int, error) {
+	n, err := z.decoder.Read(p)
+	switch err {
+	case zstd.ErrDecoderSizeExceeded, zstd.ErrFrameSizeExceeded, zstd.ErrWindowSizeExceeded:
+		err = errRequestTooLarge
+	}
+	return n, err
+}
+
+func (z *zstdBody) Close() error {
+	z.decoder.Close()
+	return nil
+}
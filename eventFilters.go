@@ -0,0 +1,121 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"regexp/syntax"
+	"strings"
+
+	"github.com/goph/emperror"
+	"github.com/xmidt-org/svalinn/rules"
+)
+
+const (
+	// eventPrefix starts the destination of every event.  The webhook
+	// service matches the registered events against the destination without
+	// it.
+	eventPrefix = "event:"
+
+	// matchAll is the event registered when there are no rules, since every
+	// event is stored.
+	matchAll = ".*"
+)
+
+// deriveEvents gets the events to register from the rules: the regex of
+// every rule, without the event prefix.  With no rules, every event is
+// stored, so every event is registered.
+func deriveEvents(ruleConfigs []rules.RuleConfig) []string {
+	if len(ruleConfigs) == 0 {
+		return []string{matchAll}
+	}
+	var events []string
+	seen := make(map[string]bool)
+	for _, r := range ruleConfigs {
+		event := r.Regex
+		switch {
+		case strings.HasPrefix(event, "^"+eventPrefix):
+			event = "^" + strings.TrimPrefix(event, "^"+eventPrefix)
+		case strings.HasPrefix(event, eventPrefix):
+			event = strings.TrimPrefix(event, eventPrefix)
+		}
+		if !seen[event] {
+			seen[event] = true
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// unreachableRules finds the rules that no registered event can match, so
+// they never receive any events.  Only the literal text that anchored
+// regexes start with is compared: a rule is unreachable when every event
+// starts with text the rule's matches can't start with.
+func unreachableRules(ruleConfigs []rules.RuleConfig, events []string) ([]rules.RuleConfig, error) {
+	type prefix struct {
+		text     string
+		anchored bool
+	}
+	eventPrefixes := make([]prefix, len(events))
+	for i, e := range events {
+		text, anchored, err := anchoredPrefix(e)
+		if err != nil {
+			return nil, emperror.WrapWith(err, "failed to parse event regex", "event", e)
+		}
+		eventPrefixes[i] = prefix{text: eventPrefix + text, anchored: anchored}
+	}
+
+	var unreachable []rules.RuleConfig
+	for _, r := range ruleConfigs {
+		ruleText, ruleAnchored, err := anchoredPrefix(r.Regex)
+		if err != nil {
+			return nil, emperror.WrapWith(err, "failed to parse rule regex", "regex", r.Regex)
+		}
+		reachable := false
+		for _, e := range eventPrefixes {
+			if !ruleAnchored || !e.anchored || strings.HasPrefix(ruleText, e.text) || strings.HasPrefix(e.text, ruleText) {
+				reachable = true
+				break
+			}
+		}
+		if !reachable {
+			unreachable = append(unreachable, r)
+		}
+	}
+	return unreachable, nil
+}
+
+// anchoredPrefix gets the literal text that every match of the regex starts
+// with, if the regex is anchored to the start of the text.
+func anchoredPrefix(regex string) (string, bool, error) {
+	re, err := syntax.Parse(regex, syntax.Perl)
+	if err != nil {
+		return "", false, err
+	}
+	re = re.Simplify()
+	if re.Op != syntax.OpConcat || re.Sub[0].Op != syntax.OpBeginText {
+		return "", false, nil
+	}
+	var text strings.Builder
+	for _, sub := range re.Sub[1:] {
+		if sub.Op != syntax.OpLiteral || sub.Flags&syntax.FoldCase != 0 {
+			break
+		}
+		text.WriteString(string(sub.Rune))
+	}
+	return text.String(), true, nil
+}
@@ -0,0 +1,96 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/svalinn/rules"
+)
+
+func TestDeriveEvents(t *testing.T) {
+	tests := []struct {
+		description    string
+		rules          []rules.RuleConfig
+		expectedEvents []string
+	}{
+		{
+			description:    "No Rules",
+			expectedEvents: []string{matchAll},
+		},
+		{
+			description: "Prefix Removed",
+			rules: []rules.RuleConfig{
+				{Regex: "^event:device-status/.*/online$"},
+				{Regex: "event:reboot-.*"},
+				{Regex: ".*/offline$"},
+			},
+			expectedEvents: []string{"^device-status/.*/online$", "reboot-.*", ".*/offline$"},
+		},
+		{
+			description: "Duplicates Skipped",
+			rules: []rules.RuleConfig{
+				{Regex: ".*/online$"},
+				{Regex: ".*/online$", Priority: "high"},
+			},
+			expectedEvents: []string{".*/online$"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert.Equal(t, tc.expectedEvents, deriveEvents(tc.rules))
+		})
+	}
+}
+
+func TestUnreachableRules(t *testing.T) {
+	tests := []struct {
+		description string
+		rule        string
+		events      []string
+		reachable   bool
+	}{
+		{description: "Match Everything", rule: "^event:device-status/", events: []string{".*"}, reachable: true},
+		{description: "Unanchored Rule", rule: ".*/online$", events: []string{"^reboot-"}, reachable: true},
+		{description: "Unanchored Event", rule: "^event:reboot-", events: []string{"device-status"}, reachable: true},
+		{description: "Same Regex", rule: "^event:device-status/", events: []string{"^device-status/"}, reachable: true},
+		{description: "Event Prefix Of Rule", rule: "^event:device-status/.*/online$", events: []string{"^device-status"}, reachable: true},
+		{description: "Rule Prefix Of Event", rule: "^event:reboot", events: []string{"^reboot-pending$"}, reachable: true},
+		{description: "Different Prefix", rule: "^event:reboot-", events: []string{"^device-status"}},
+		{description: "Prefix Not In Event", rule: "^event:reboot$", events: []string{"^event:"}},
+		{description: "Case Insensitive", rule: "(?i)^event:MAC:", events: []string{"^reboot"}, reachable: true},
+		{description: "Any Of Several Events", rule: "^event:reboot-", events: []string{"^device-status", "^reboot-"}, reachable: true},
+		{description: "No Events", rule: ".*"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			unreachable, err := unreachableRules([]rules.RuleConfig{{Regex: tc.rule}}, tc.events)
+			require.Nil(t, err)
+			assert.Equal(t, tc.reachable, len(unreachable) == 0)
+		})
+	}
+
+	t.Run("Invalid Regex", func(t *testing.T) {
+		_, err := unreachableRules([]rules.RuleConfig{{Regex: "(("}}, []string{".*"})
+		assert.NotNil(t, err)
+		_, err = unreachableRules([]rules.RuleConfig{{Regex: ".*"}}, []string{"(("})
+		assert.NotNil(t, err)
+	})
+}
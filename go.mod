@@ -3,21 +3,27 @@ module github.com/xmidt-org/svalinn
 go 1.12
 
 require (
+	github.com/IBM/sarama v1.43.3
+	github.com/InVisionApp/go-health v2.1.0+incompatible
 	github.com/InVisionApp/go-health/v2 v2.1.4
 	github.com/cenkalti/backoff/v3 v3.2.2
 	github.com/go-kit/kit v0.13.0
 	github.com/goph/emperror v0.17.3-0.20190703203600-60a8d9faa17b
 	github.com/gorilla/mux v1.8.1
 	github.com/justinas/alice v1.2.0
+	github.com/klauspost/compress v1.17.9
 	github.com/lestrrat-go/jwx/v2 v2.0.21 // indirect
+	github.com/spf13/cast v1.6.0
 	github.com/spf13/pflag v1.0.5
 	github.com/spf13/viper v1.19.0
 	github.com/stretchr/testify v1.9.0
 	github.com/xmidt-org/bascule v0.11.0
+	github.com/xmidt-org/clortho v0.0.4
 	github.com/xmidt-org/codex-db v0.7.3
 	github.com/xmidt-org/voynicrypto v0.1.1
 	github.com/xmidt-org/webpa-common/v2 v2.0.7
 	github.com/xmidt-org/wrp-go/v3 v3.1.4
 	github.com/xmidt-org/wrp-listener v0.2.5
-
+	google.golang.org/grpc v1.62.1
+	google.golang.org/protobuf v1.33.0
 )
@@ -0,0 +1,457 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/goph/emperror"
+	"github.com/justinas/alice"
+	"github.com/xmidt-org/svalinn/requestParser"
+	"github.com/xmidt-org/webpa-common/v2/logging"
+	"github.com/xmidt-org/wrp-go/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const (
+	defaultMaxThrottle  = 30 * time.Second
+	grpcShutdownTimeout = 10 * time.Second
+
+	ingestServiceName  = "svalinn.v1.Ingest"
+	ingestMethod       = "Ingest"
+	ingestStreamMethod = "IngestStream"
+
+	retryAfterKey = "retry-after"
+	acceptedKey   = "accepted-count"
+)
+
+// GRPCConfig sets up the gRPC ingestion service, which takes the same
+// msgpack WRP messages as the webhook endpoint.
+type GRPCConfig struct {
+	// Address is where the gRPC server listens.  If empty, the service isn't
+	// served.
+	Address string
+
+	// TLS serves the service with the certificates of the tls section,
+	// including requiring client certificates if it has client CAs.
+	TLS bool
+
+	// MaxThrottle is how long a stream waits for room in the parsing queue
+	// before it's ended.  Defaults to 30s.
+	MaxThrottle time.Duration
+}
+
+// ingestServer is the gRPC ingestion service.  Messages are carried as the
+// msgpack encoded bytes of a BytesValue, so the service doesn't need any
+// generated code.
+type ingestServer interface {
+	Ingest(context.Context, *wrapperspb.BytesValue) (*emptypb.Empty, error)
+	IngestStream(grpc.ServerStream) error
+}
+
+var ingestServiceDesc = grpc.ServiceDesc{
+	ServiceName: ingestServiceName,
+	HandlerType: (*ingestServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: ingestMethod,
+			Handler:    ingestHandler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    ingestStreamMethod,
+			Handler:       ingestStreamHandler,
+			ClientStreams: true,
+		},
+	},
+}
+
+func ingestHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(wrapperspb.BytesValue)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ingestServer).Ingest(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/" + ingestServiceName + "/" + ingestMethod,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ingestServer).Ingest(ctx, req.(*wrapperspb.BytesValue))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func ingestStreamHandler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ingestServer).IngestStream(stream)
+}
+
+// grpcIngest queues the messages it receives to be parsed, answering them
+// the same way the webhook endpoint does.
+type grpcIngest struct {
+	app         *App
+	maxThrottle time.Duration
+	logger      log.Logger
+}
+
+// Ingest accepts a single message.  If it isn't accepted, the error has a
+// retry-after trailer in seconds.
+func (g *grpcIngest) Ingest(ctx context.Context, in *wrapperspb.BytesValue) (*emptypb.Empty, error) {
+	begin := time.Now()
+	if g.app.isShuttingDown() {
+		g.app.timeTracker.TrackTime(time.Since(begin))
+		return nil, g.rejection(ctx, requestParser.ErrShuttingDown)
+	}
+	message, err := g.decode(ctx, in)
+	if err != nil {
+		g.app.timeTracker.TrackTime(time.Since(begin))
+		return nil, err
+	}
+	err = g.app.parser.Parse(requestParser.WrpWithTime{Message: message, Beginning: begin})
+	if err != nil {
+		logging.Warn(g.logger).Log(logging.ErrorKey(), err.Error())
+		g.app.timeTracker.TrackTime(time.Since(begin))
+		return nil, g.rejection(ctx, err)
+	}
+	return new(emptypb.Empty), nil
+}
+
+// IngestStream accepts messages until the client closes the stream, then
+// answers with how many were accepted.  While the parsing queue is full, the
+// stream stops reading, so the client is held back by the stream's flow
+// control.  A stream held back for longer than the max throttle, or sent
+// while svalinn can't accept events, is ended with an error.  Errors have
+// a trailer with how many messages were accepted, along with the
+// retry-after trailer if the client should try again.
+func (g *grpcIngest) IngestStream(stream grpc.ServerStream) error {
+	ctx := stream.Context()
+	var accepted uint64
+	defer func() {
+		stream.SetTrailer(metadata.Pairs(acceptedKey, strconv.FormatUint(accepted, 10)))
+	}()
+	for {
+		in := new(wrapperspb.BytesValue)
+		err := stream.RecvMsg(in)
+		if err == io.EOF {
+			return stream.SendMsg(wrapperspb.UInt64(accepted))
+		}
+		if err != nil {
+			return err
+		}
+
+		begin := time.Now()
+		message, err := g.decode(ctx, in)
+		if err != nil {
+			g.app.timeTracker.TrackTime(time.Since(begin))
+			return err
+		}
+		if err = g.throttle(ctx, requestParser.WrpWithTime{Message: message, Beginning: begin}); err != nil {
+			g.app.timeTracker.TrackTime(time.Since(begin))
+			return err
+		}
+		accepted++
+	}
+}
+
+// throttle queues the message to be parsed, waiting for room in the queue as
+// long as the max throttle allows.
+func (g *grpcIngest) throttle(ctx context.Context, w requestParser.WrpWithTime) error {
+	var throttled time.Duration
+	for {
+		err := requestParser.ErrShuttingDown
+		if !g.app.isShuttingDown() {
+			err = g.app.parser.Parse(w)
+		}
+		if err == nil {
+			return nil
+		}
+		code, retryAfter := g.app.rejection(err)
+		if code != http.StatusTooManyRequests || throttled+retryAfter > g.maxThrottle {
+			logging.Warn(g.logger).Log(logging.MessageKey(), "Ending stream, event wasn't accepted", logging.ErrorKey(), err.Error(),
+				"throttled", throttled)
+			return g.rejection(ctx, err)
+		}
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-time.After(retryAfter):
+		}
+		throttled += retryAfter
+	}
+}
+
+// decode gets the message and checks that the caller is allowed to send it.
+func (g *grpcIngest) decode(ctx context.Context, in *wrapperspb.BytesValue) (wrp.Message, error) {
+	var message wrp.Message
+	if err := wrp.NewDecoderBytes(in.GetValue(), wrp.Msgpack).Decode(&message); err != nil {
+		logging.Error(g.logger).Log(logging.MessageKey(), "Could not decode gRPC message", logging.ErrorKey(), err.Error())
+		return message, status.Error(codes.InvalidArgument, "message isn't msgpack encoded wrp")
+	}
+	if err := authorizePartners(ctx, &message); err != nil {
+		logging.Error(g.logger, emperror.Context(err)...).Log(logging.MessageKey(), "Event isn't allowed by the token", logging.ErrorKey(), err.Error())
+		return message, status.Error(codes.PermissionDenied, err.Error())
+	}
+	return message, nil
+}
+
+// rejection turns an event that wasn't accepted into the status the webhook
+// endpoint would answer with, telling the client when to try again.
+func (g *grpcIngest) rejection(ctx context.Context, err error) error {
+	httpStatus, retryAfter := g.app.rejection(err)
+	grpc.SetTrailer(ctx, metadata.Pairs(retryAfterKey, retryAfterSeconds(retryAfter)))
+	code := codes.ResourceExhausted
+	if httpStatus == http.StatusServiceUnavailable {
+		code = codes.Unavailable
+	}
+	return status.Error(code, err.Error())
+}
+
+type authResultKey struct{}
+
+// authResult holds the context the auth chain authenticated.
+type authResult struct {
+	ctx context.Context
+}
+
+// grpcAuth authenticates calls with the webhook endpoint's auth chain,
+// presenting each call as a request with the call's metadata as headers and
+// its connection's TLS state.  Only unary calls have a body, so streams are
+// authenticated with a chain that doesn't accept signatures, and are rejected
+// when there's no such chain.
+type grpcAuth struct {
+	unaryHandler  http.Handler
+	streamHandler http.Handler
+}
+
+func newGRPCAuth(unaryChain alice.Chain, streamChain *alice.Chain) *grpcAuth {
+	a := &grpcAuth{unaryHandler: unaryChain.Then(authenticated)}
+	if streamChain != nil {
+		a.streamHandler = streamChain.Then(authenticated)
+	}
+	return a
+}
+
+// authenticated records the context of a request the auth chain let through.
+var authenticated = http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+	if result, ok := r.Context().Value(authResultKey{}).(*authResult); ok {
+		result.ctx = r.Context()
+	}
+})
+
+func authenticate(ctx context.Context, handler http.Handler, method string, body []byte) (context.Context, error) {
+	req, err := http.NewRequest(http.MethodPost, method, bytes.NewReader(body))
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	result := new(authResult)
+	req = req.WithContext(context.WithValue(ctx, authResultKey{}, result))
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for k, values := range md {
+			if strings.HasPrefix(k, ":") {
+				continue
+			}
+			for _, v := range values {
+				req.Header.Add(k, v)
+			}
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		if p.Addr != nil {
+			req.RemoteAddr = p.Addr.String()
+		}
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			state := info.State
+			req.TLS = &state
+		}
+	}
+
+	recorder := &statusRecorder{header: make(http.Header)}
+	handler.ServeHTTP(recorder, req)
+	if result.ctx == nil {
+		return nil, status.Error(authCode(recorder.status), "call wasn't authenticated")
+	}
+	return result.ctx, nil
+}
+
+func (a *grpcAuth) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	var body []byte
+	if in, ok := req.(*wrapperspb.BytesValue); ok {
+		body = in.GetValue()
+	}
+	ctx, err := authenticate(ctx, a.unaryHandler, info.FullMethod, body)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a *grpcAuth) stream(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if a.streamHandler == nil {
+		return status.Error(codes.Unauthenticated, "streams can't be authenticated with a signature")
+	}
+	ctx, err := authenticate(stream.Context(), a.streamHandler, info.FullMethod, nil)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authenticatedStream{ServerStream: stream, ctx: ctx})
+}
+
+// authenticatedStream is a stream with the context the auth chain
+// authenticated.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+// authCode gets the code for the status the auth chain answered with.
+func authCode(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	default:
+		return codes.Unauthenticated
+	}
+}
+
+// statusRecorder keeps the status the auth chain answered with, discarding
+// the rest of the response.
+type statusRecorder struct {
+	header http.Header
+	status int
+}
+
+func (r *statusRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return len(b), nil
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+// grpcServer serves the ingestion service.
+type grpcServer struct {
+	server   *grpc.Server
+	listener net.Listener
+	reloader *certReloader
+	stop     chan struct{}
+	logger   log.Logger
+}
+
+// newGRPCServer sets up the ingestion service at the configured address,
+// authenticating unary calls with the first chain and streams with the second.
+// Streams are rejected when there's no stream chain.
+func newGRPCServer(config GRPCConfig, tlsConfig TLSConfig, app *App, unaryChain alice.Chain, streamChain *alice.Chain, logger log.Logger) (*grpcServer, error) {
+	maxSize := app.maxRequestSize
+	if maxSize <= 0 {
+		maxSize = defaultMaxRequestSize
+	}
+	auth := newGRPCAuth(unaryChain, streamChain)
+	options := []grpc.ServerOption{
+		grpc.MaxRecvMsgSize(int(maxSize)),
+		grpc.UnaryInterceptor(auth.unary),
+		grpc.StreamInterceptor(auth.stream),
+	}
+	g := &grpcServer{
+		stop:   make(chan struct{}),
+		logger: logger,
+	}
+	if config.TLS {
+		reloader, err := newCertReloader(tlsConfig, logger)
+		if err != nil {
+			return nil, err
+		}
+		g.reloader = reloader
+		options = append(options, grpc.Creds(credentials.NewTLS(reloader.tlsConfig())))
+	}
+
+	listener, err := net.Listen("tcp", config.Address)
+	if err != nil {
+		return nil, emperror.WrapWith(err, "failed to listen", "address", config.Address)
+	}
+	g.listener = listener
+	g.server = grpc.NewServer(options...)
+
+	maxThrottle := config.MaxThrottle
+	if maxThrottle <= 0 {
+		maxThrottle = defaultMaxThrottle
+	}
+	g.server.RegisterService(&ingestServiceDesc, &grpcIngest{app: app, maxThrottle: maxThrottle, logger: logger})
+	return g, nil
+}
+
+func (g *grpcServer) Start() {
+	if g.reloader != nil {
+		go g.reloader.watch(g.stop)
+	}
+	go func() {
+		err := g.server.Serve(g.listener)
+		if err != nil && err != grpc.ErrServerStopped {
+			logging.Error(g.logger).Log(logging.MessageKey(), "gRPC server exited", logging.ErrorKey(), err.Error())
+		}
+	}()
+}
+
+// Stop waits for the calls being served to finish, ending any that are
+// still going after the shutdown timeout.
+func (g *grpcServer) Stop() {
+	close(g.stop)
+	done := make(chan struct{})
+	go func() {
+		g.server.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(grpcShutdownTimeout):
+		g.server.Stop()
+		<-done
+	}
+}
@@ -0,0 +1,250 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/justinas/alice"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/svalinn/requestParser"
+	"github.com/xmidt-org/webpa-common/v2/logging"
+	"github.com/xmidt-org/wrp-go/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const (
+	ingestFullMethod       = "/" + ingestServiceName + "/" + ingestMethod
+	ingestStreamFullMethod = "/" + ingestServiceName + "/" + ingestStreamMethod
+)
+
+// requireToken only lets through requests with the test's authorization.
+func requireToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Basic dGVzdDp0ZXN0" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func startGRPCServer(t *testing.T, parser parser, config GRPCConfig, streamChain *alice.Chain) (*grpc.ClientConn, *App) {
+	timeTracker := new(mockTimeTracker)
+	timeTracker.On("TrackTime", mock.Anything)
+	app := &App{
+		parser:       parser,
+		logger:       logging.NewTestLogger(nil, t),
+		timeTracker:  timeTracker,
+		loadShedding: LoadSheddingConfig{MinRetryAfter: time.Millisecond, MaxRetryAfter: 2 * time.Millisecond},
+	}
+	config.Address = "127.0.0.1:0"
+	g, err := newGRPCServer(config, TLSConfig{}, app, alice.New(requireToken), streamChain, logging.NewTestLogger(nil, t))
+	require.Nil(t, err)
+	g.Start()
+	t.Cleanup(g.Stop)
+
+	conn, err := grpc.Dial(g.listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.Nil(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn, app
+}
+
+func encodeMessage(t *testing.T, destination string) *wrapperspb.BytesValue {
+	var b []byte
+	require.Nil(t, wrp.NewEncoderBytes(&b, wrp.Msgpack).Encode(&wrp.Message{
+		Type:        wrp.SimpleEventMessageType,
+		Source:      "mac:112233445566",
+		Destination: destination,
+	}))
+	return wrapperspb.Bytes(b)
+}
+
+func authorized() context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Basic dGVzdDp0ZXN0")
+}
+
+func TestGRPCIngest(t *testing.T) {
+	tests := []struct {
+		description        string
+		ctx                context.Context
+		message            *wrapperspb.BytesValue
+		parseErr           error
+		shuttingDown       bool
+		expectedCode       codes.Code
+		expectedRetryAfter string
+	}{
+		{
+			description:  "Accepted",
+			ctx:          authorized(),
+			expectedCode: codes.OK,
+		},
+		{
+			description:        "Throttled",
+			ctx:                authorized(),
+			parseErr:           requestParser.ErrQueueFull,
+			expectedCode:       codes.ResourceExhausted,
+			expectedRetryAfter: "1",
+		},
+		{
+			description:        "Shutting Down",
+			ctx:                authorized(),
+			shuttingDown:       true,
+			expectedCode:       codes.Unavailable,
+			expectedRetryAfter: "1",
+		},
+		{
+			description:  "Not Msgpack",
+			ctx:          authorized(),
+			message:      wrapperspb.Bytes([]byte("not msgpack")),
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			description:  "Unauthenticated",
+			ctx:          context.Background(),
+			expectedCode: codes.Unauthenticated,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			parser := new(mockParser)
+			parser.On("Parse", mock.MatchedBy(func(w requestParser.WrpWithTime) bool {
+				return w.Message.Destination == "event:device-status/mac:112233445566/online" && !w.Beginning.IsZero()
+			})).Return(tc.parseErr)
+			parser.On("DrainTime").Return(time.Duration(0), false).Maybe()
+			conn, app := startGRPCServer(t, parser, GRPCConfig{}, nil)
+			if tc.shuttingDown {
+				app.startShutdown()
+			}
+
+			message := tc.message
+			if message == nil {
+				message = encodeMessage(t, "event:device-status/mac:112233445566/online")
+			}
+			var trailer metadata.MD
+			err := conn.Invoke(tc.ctx, ingestFullMethod, message, new(emptypb.Empty), grpc.Trailer(&trailer))
+			assert.Equal(tc.expectedCode, status.Code(err))
+			if tc.expectedRetryAfter != "" {
+				assert.Equal([]string{tc.expectedRetryAfter}, trailer.Get(retryAfterKey))
+			}
+		})
+	}
+}
+
+func TestGRPCIngestStream(t *testing.T) {
+	tests := []struct {
+		description      string
+		parse            func(*mockParser)
+		ctx              context.Context
+		signatureOnly    bool
+		expectedCode     codes.Code
+		expectedAccepted uint64
+	}{
+		{
+			description: "Accepted",
+			parse: func(p *mockParser) {
+				p.On("Parse", mock.Anything).Return(nil).Times(3)
+			},
+			ctx:              authorized(),
+			expectedCode:     codes.OK,
+			expectedAccepted: 3,
+		},
+		{
+			description: "Waits For Room",
+			parse: func(p *mockParser) {
+				p.On("Parse", mock.Anything).Return(nil).Once()
+				p.On("Parse", mock.Anything).Return(requestParser.ErrQueueFull).Twice()
+				p.On("Parse", mock.Anything).Return(nil).Twice()
+			},
+			ctx:              authorized(),
+			expectedCode:     codes.OK,
+			expectedAccepted: 3,
+		},
+		{
+			description: "Throttled Too Long",
+			parse: func(p *mockParser) {
+				p.On("Parse", mock.Anything).Return(nil).Once()
+				p.On("Parse", mock.Anything).Return(requestParser.ErrQueueFull)
+			},
+			ctx:              authorized(),
+			expectedCode:     codes.ResourceExhausted,
+			expectedAccepted: 1,
+		},
+		{
+			description:  "Unauthenticated",
+			parse:        func(*mockParser) {},
+			ctx:          context.Background(),
+			expectedCode: codes.Unauthenticated,
+		},
+		{
+			description:   "Signature Only",
+			parse:         func(*mockParser) {},
+			ctx:           authorized(),
+			signatureOnly: true,
+			expectedCode:  codes.Unauthenticated,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			parser := new(mockParser)
+			tc.parse(parser)
+			parser.On("DrainTime").Return(time.Duration(0), false).Maybe()
+			var streamChain *alice.Chain
+			if !tc.signatureOnly {
+				chain := alice.New(requireToken)
+				streamChain = &chain
+			}
+			conn, _ := startGRPCServer(t, parser, GRPCConfig{MaxThrottle: 10 * time.Millisecond}, streamChain)
+
+			stream, err := conn.NewStream(tc.ctx, &ingestServiceDesc.Streams[0], ingestStreamFullMethod)
+			require.Nil(err)
+			for i := 0; i < 3; i++ {
+				if stream.SendMsg(encodeMessage(t, "event:device-status/mac:112233445566/online")) != nil {
+					// the server ended the stream, which RecvMsg reports.
+					break
+				}
+			}
+			require.Nil(stream.CloseSend())
+			accepted := new(wrapperspb.UInt64Value)
+			err = stream.RecvMsg(accepted)
+			assert.Equal(tc.expectedCode, status.Code(err))
+			if err == nil {
+				assert.Equal(tc.expectedAccepted, accepted.GetValue())
+			}
+			if tc.expectedCode != codes.Unauthenticated {
+				assert.Equal([]string{strconv.FormatUint(tc.expectedAccepted, 10)}, stream.Trailer().Get(acceptedKey))
+			}
+			parser.AssertExpectations(t)
+		})
+	}
+}
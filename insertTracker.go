@@ -0,0 +1,89 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"sync"
+
+	"github.com/xmidt-org/codex-db"
+)
+
+// insertTracker tells the request parser when the records it inserted have
+// been written, since the batch inserters only queue them.  It wraps the
+// inserters the batch inserters write to.  A record is known by its data,
+// which is never empty and is created for each record, so the address of its
+// first byte is the same wherever the record is copied.
+type insertTracker struct {
+	lock    sync.Mutex
+	pending map[*byte]func(error)
+}
+
+func newInsertTracker() *insertTracker {
+	return &insertTracker{pending: make(map[*byte]func(error))}
+}
+
+// Track calls done with the result of writing the batch the record ends up
+// in.  untrack forgets the record, for when it couldn't be inserted.
+func (t *insertTracker) Track(record db.Record, done func(error)) (untrack func()) {
+	if len(record.Data) == 0 {
+		return func() {}
+	}
+	key := &record.Data[0]
+	t.lock.Lock()
+	t.pending[key] = done
+	t.lock.Unlock()
+	return func() {
+		t.lock.Lock()
+		delete(t.pending, key)
+		t.lock.Unlock()
+	}
+}
+
+// wrap gets an inserter that reports the records it writes to the tracker.
+func (t *insertTracker) wrap(inserter db.Inserter) db.Inserter {
+	return &trackedInserter{inserter: inserter, tracker: t}
+}
+
+func (t *insertTracker) finish(records []db.Record, err error) {
+	var finished []func(error)
+	t.lock.Lock()
+	for _, r := range records {
+		if len(r.Data) == 0 {
+			continue
+		}
+		if done, ok := t.pending[&r.Data[0]]; ok {
+			delete(t.pending, &r.Data[0])
+			finished = append(finished, done)
+		}
+	}
+	t.lock.Unlock()
+	for _, done := range finished {
+		done(err)
+	}
+}
+
+type trackedInserter struct {
+	inserter db.Inserter
+	tracker  *insertTracker
+}
+
+func (i *trackedInserter) InsertRecords(records ...db.Record) error {
+	err := i.inserter.InsertRecords(records...)
+	i.tracker.finish(records, err)
+	return err
+}
@@ -0,0 +1,59 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xmidt-org/codex-db"
+)
+
+func TestInsertTracker(t *testing.T) {
+	assert := assert.New(t)
+	errInsert := errors.New("insert failed")
+	records := []db.Record{
+		{DeviceID: "mac:112233445566", Data: []byte("first")},
+		{DeviceID: "mac:112233445566", Data: []byte("second")},
+		{DeviceID: "mac:112233445566", Data: []byte("untracked")},
+		{DeviceID: "mac:112233445566", Data: []byte("forgotten")},
+	}
+	tracker := newInsertTracker()
+	results := make(map[string][]error)
+	track := func(r db.Record) func() {
+		return tracker.Track(r, func(err error) {
+			results[string(r.Data)] = append(results[string(r.Data)], err)
+		})
+	}
+	track(records[0])
+	track(records[1])
+	track(records[3])()
+
+	inserter := new(mockDbInserter)
+	inserter.On("InsertRecords", records[:1]).Return(nil).Once()
+	inserter.On("InsertRecords", records[1:]).Return(errInsert).Once()
+	tracked := tracker.wrap(inserter)
+
+	// the records are copied on their way to the inserter, like in a batch.
+	assert.Nil(tracked.InsertRecords(append([]db.Record(nil), records[:1]...)...))
+	assert.Equal(errInsert, tracked.InsertRecords(append([]db.Record(nil), records[1:]...)...))
+	assert.Equal(map[string][]error{"first": {nil}, "second": {errInsert}}, results)
+	assert.Empty(tracker.pending)
+	inserter.AssertExpectations(t)
+}
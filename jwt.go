@@ -0,0 +1,184 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/goph/emperror"
+	"github.com/justinas/alice"
+	"github.com/spf13/cast"
+	"github.com/xmidt-org/bascule"
+	"github.com/xmidt-org/bascule/basculechecks"
+	"github.com/xmidt-org/bascule/basculehttp"
+	"github.com/xmidt-org/clortho"
+	"github.com/xmidt-org/wrp-go/v3"
+)
+
+const (
+	jwtTokenType = "jwt"
+	allPartners  = "*"
+)
+
+var (
+	errNoJWKS               = errors.New("no JWKS location given")
+	errNoCapabilities       = errors.New("token has no capabilities")
+	errCapabilityNotAllowed = errors.New("no capability in the token is allowed to send events")
+	errNoPartners           = errors.New("token has no allowed partners")
+	errPartnerNotAllowed    = errors.New("event has a partner id the token isn't allowed to send")
+)
+
+// JWTConfig sets up validating JWTs sent as bearer tokens, so services other
+// than the one Svalinn registers with can send events.
+type JWTConfig struct {
+	// JWKS is the file path or URL of the JSON Web Key Set used to verify
+	// tokens.  The set is fetched again when a token has a key id that
+	// hasn't been seen yet.
+	JWKS string
+
+	// DefaultKeyID is used for tokens without a key id.
+	DefaultKeyID string
+
+	// Leeway is the allowed clock skew when checking the time claims.
+	Leeway bascule.Leeway
+
+	// Capabilities are regular expressions.  A token must have a capability
+	// that matches one of them.  If empty, capabilities aren't checked.
+	Capabilities []string
+}
+
+// newJWTConstructor creates the middleware that parses bearer tokens and the
+// enforcer that checks their capabilities.
+func newJWTConstructor(config JWTConfig, cOptions []basculehttp.COption, eOptions []basculehttp.EOption) (alice.Chain, error) {
+	if config.JWKS == "" {
+		return alice.Chain{}, errNoJWKS
+	}
+	capabilities := make([]*regexp.Regexp, 0, len(config.Capabilities))
+	for _, c := range config.Capabilities {
+		r, err := regexp.Compile(c)
+		if err != nil {
+			return alice.Chain{}, emperror.WrapWith(err, "failed to compile capability", "capability", c)
+		}
+		capabilities = append(capabilities, r)
+	}
+
+	// the JWKS location is used as the key template, so every key is
+	// resolved by fetching the set and picking the key with the right id.
+	resolver, err := clortho.NewResolver(clortho.WithKeyIDTemplate(config.JWKS), clortho.WithKeyRing(clortho.NewKeyRing()))
+	if err != nil {
+		return alice.Chain{}, emperror.Wrap(err, "failed to create key resolver")
+	}
+	btf := basculehttp.BearerTokenFactory{
+		DefaultKeyID: config.DefaultKeyID,
+		Resolver:     resolver,
+		Parser:       bascule.DefaultJWTParser,
+		Leeway:       config.Leeway,
+	}
+
+	cOptions = append(cOptions,
+		basculehttp.WithTokenFactory(basculehttp.BearerAuthorization, btf),
+		basculehttp.WithHeaderName(basculehttp.DefaultHeaderName),
+		basculehttp.WithHeaderDelimiter(basculehttp.DefaultHeaderDelimiter),
+	)
+	eOptions = append(eOptions,
+		basculehttp.WithRules(basculehttp.BearerAuthorization, capabilityCheck(capabilities)),
+	)
+	return alice.New(basculehttp.NewConstructor(cOptions...), basculehttp.NewEnforcer(eOptions...)), nil
+}
+
+// capabilityCheck makes sure the token has a capability matching one of the
+// allowed capabilities.
+func capabilityCheck(allowed []*regexp.Regexp) bascule.Validator {
+	return bascule.ValidatorFunc(func(_ context.Context, token bascule.Token) error {
+		if len(allowed) == 0 {
+			return nil
+		}
+		val, ok := bascule.GetNestedAttribute(token.Attributes(), basculechecks.CapabilityKeys()...)
+		if !ok {
+			return errNoCapabilities
+		}
+		capabilities, err := cast.ToStringSliceE(val)
+		if err != nil {
+			return emperror.Wrap(errNoCapabilities, err.Error())
+		}
+		for _, c := range capabilities {
+			for _, r := range allowed {
+				if r.MatchString(c) {
+					return nil
+				}
+			}
+		}
+		return errCapabilityNotAllowed
+	})
+}
+
+// selectAuth sends requests with a bearer token through the JWT chain and the
+// rest through the fallback chain.
+func selectAuth(jwt alice.Chain, fallback alice.Chain) alice.Constructor {
+	return func(next http.Handler) http.Handler {
+		jwtHandler := jwt.Then(next)
+		fallbackHandler := fallback.Then(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasPrefix(r.Header.Get(basculehttp.DefaultHeaderName), string(basculehttp.BearerAuthorization)+basculehttp.DefaultHeaderDelimiter) {
+				jwtHandler.ServeHTTP(w, r)
+				return
+			}
+			fallbackHandler.ServeHTTP(w, r)
+		})
+	}
+}
+
+// authorizePartners checks the event's partner ids against the partners a JWT
+// allows.  If the event has no partner ids, it gets the token's partners.
+// Requests not authenticated with a JWT aren't checked.
+func authorizePartners(ctx context.Context, msg *wrp.Message) error {
+	auth, ok := bascule.FromContext(ctx)
+	if !ok || auth.Token == nil || auth.Token.Type() != jwtTokenType {
+		return nil
+	}
+	val, ok := bascule.GetNestedAttribute(auth.Token.Attributes(), basculechecks.PartnerKeys()...)
+	if !ok {
+		return errNoPartners
+	}
+	partners, err := cast.ToStringSliceE(val)
+	if err != nil || len(partners) == 0 {
+		return errNoPartners
+	}
+
+	allowed := make(map[string]bool, len(partners))
+	for _, p := range partners {
+		allowed[p] = true
+	}
+	if allowed[allPartners] {
+		return nil
+	}
+	if len(msg.PartnerIDs) == 0 {
+		msg.PartnerIDs = partners
+		return nil
+	}
+	for _, id := range msg.PartnerIDs {
+		if !allowed[id] {
+			return emperror.With(errPartnerNotAllowed, "partnerID", id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,323 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/justinas/alice"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/bascule"
+	"github.com/xmidt-org/wrp-go/v3"
+)
+
+func writeJWKS(t *testing.T, kid string, key *rsa.PublicKey) []byte {
+	jwks := map[string]interface{}{
+		"keys": []map[string]interface{}{
+			{
+				"kty": "RSA",
+				"kid": kid,
+				"alg": "RS256",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			},
+		},
+	}
+	b, err := json.Marshal(jwks)
+	require.Nil(t, err)
+	return b
+}
+
+func signJWT(t *testing.T, kid string, key *rsa.PrivateKey, claims map[string]interface{}) string {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": kid})
+	require.Nil(t, err)
+	payload, err := json.Marshal(claims)
+	require.Nil(t, err)
+	signingString := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	hashed := sha256.Sum256([]byte(signingString))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hashed[:])
+	require.Nil(t, err)
+	return signingString + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestNewJWTConstructor(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.Nil(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.Nil(t, err)
+	jwks := writeJWKS(t, "k1", &key.PublicKey)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(jwks)
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "jwks")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	jwksFile := filepath.Join(dir, "keys.json")
+	require.Nil(t, ioutil.WriteFile(jwksFile, jwks, 0600))
+
+	goodClaims := map[string]interface{}{
+		"sub":          "test-service",
+		"exp":          time.Now().Add(time.Hour).Unix(),
+		"capabilities": []string{"x1:svalinn:events:post"},
+	}
+	expiredClaims := map[string]interface{}{
+		"sub":          "test-service",
+		"exp":          time.Now().Add(-time.Hour).Unix(),
+		"capabilities": []string{"x1:svalinn:events:post"},
+	}
+	wrongCapabilityClaims := map[string]interface{}{
+		"sub":          "test-service",
+		"exp":          time.Now().Add(time.Hour).Unix(),
+		"capabilities": []string{"x1:other:thing"},
+	}
+
+	tests := []struct {
+		description  string
+		jwks         string
+		capabilities []string
+		token        string
+		expectedErr  error
+		expectedCode int
+	}{
+		{
+			description:  "URL Success",
+			jwks:         server.URL,
+			capabilities: []string{"x1:svalinn:.*"},
+			token:        signJWT(t, "k1", key, goodClaims),
+			expectedCode: http.StatusOK,
+		},
+		{
+			description:  "File Success",
+			jwks:         jwksFile,
+			capabilities: []string{"x1:svalinn:.*"},
+			token:        signJWT(t, "k1", key, goodClaims),
+			expectedCode: http.StatusOK,
+		},
+		{
+			description:  "No Capability Check Success",
+			jwks:         server.URL,
+			token:        signJWT(t, "k1", key, wrongCapabilityClaims),
+			expectedCode: http.StatusOK,
+		},
+		{
+			description:  "Capability Not Allowed",
+			jwks:         server.URL,
+			capabilities: []string{"x1:svalinn:.*"},
+			token:        signJWT(t, "k1", key, wrongCapabilityClaims),
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			description:  "Expired Token",
+			jwks:         server.URL,
+			token:        signJWT(t, "k1", key, expiredClaims),
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			description:  "Wrong Key",
+			jwks:         server.URL,
+			token:        signJWT(t, "k1", otherKey, goodClaims),
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			description:  "No Token",
+			jwks:         server.URL,
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			description: "No JWKS",
+			expectedErr: errNoJWKS,
+		},
+		{
+			description:  "Invalid Capability",
+			jwks:         server.URL,
+			capabilities: []string{"("},
+			expectedErr:  errors.New("failed to compile capability"),
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			chain, err := newJWTConstructor(JWTConfig{JWKS: tc.jwks, Capabilities: tc.capabilities}, nil, nil)
+			if tc.expectedErr != nil {
+				require.NotNil(t, err)
+				assert.Contains(err.Error(), tc.expectedErr.Error())
+				return
+			}
+			require.Nil(t, err)
+
+			handler := chain.ThenFunc(func(w http.ResponseWriter, r *http.Request) {
+				auth, ok := bascule.FromContext(r.Context())
+				assert.True(ok)
+				assert.Equal(jwtTokenType, auth.Token.Type())
+				w.WriteHeader(http.StatusOK)
+			})
+			request := httptest.NewRequest(http.MethodPost, "/", nil)
+			if tc.token != "" {
+				request.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, request)
+			assert.Equal(tc.expectedCode, rr.Code)
+		})
+	}
+}
+
+func TestSelectAuth(t *testing.T) {
+	tests := []struct {
+		description   string
+		authorization string
+		expectedCode  int
+	}{
+		{
+			description:   "Bearer Token",
+			authorization: "Bearer abc",
+			expectedCode:  http.StatusOK,
+		},
+		{
+			description:   "Basic Auth",
+			authorization: "Basic abc",
+			expectedCode:  http.StatusTeapot,
+		},
+		{
+			description:  "No Authorization",
+			expectedCode: http.StatusTeapot,
+		},
+	}
+	status := func(code int) alice.Constructor {
+		return func(http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(code)
+			})
+		}
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			handler := alice.New(selectAuth(alice.New(status(http.StatusOK)), alice.New(status(http.StatusTeapot)))).Then(http.NotFoundHandler())
+			request := httptest.NewRequest(http.MethodPost, "/", nil)
+			if tc.authorization != "" {
+				request.Header.Set("Authorization", tc.authorization)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, request)
+			assert.Equal(t, tc.expectedCode, rr.Code)
+		})
+	}
+}
+
+func TestAuthorizePartners(t *testing.T) {
+	jwtToken := func(attributes map[string]interface{}) bascule.Token {
+		return bascule.NewToken(jwtTokenType, "test", bascule.NewAttributes(attributes))
+	}
+	partners := func(p ...string) map[string]interface{} {
+		return map[string]interface{}{
+			"allowedResources": map[string]interface{}{
+				"allowedPartners": p,
+			},
+		}
+	}
+
+	tests := []struct {
+		description        string
+		token              bascule.Token
+		partnerIDs         []string
+		expectedPartnerIDs []string
+		expectedErr        error
+	}{
+		{
+			description:        "No Authentication",
+			partnerIDs:         []string{"comcast"},
+			expectedPartnerIDs: []string{"comcast"},
+		},
+		{
+			description:        "Not JWT",
+			token:              bascule.NewToken("Sha1", "abc", bascule.NewAttributes(nil)),
+			partnerIDs:         []string{"comcast"},
+			expectedPartnerIDs: []string{"comcast"},
+		},
+		{
+			description:        "Allowed",
+			token:              jwtToken(partners("comcast", "sky")),
+			partnerIDs:         []string{"sky"},
+			expectedPartnerIDs: []string{"sky"},
+		},
+		{
+			description:        "Wildcard",
+			token:              jwtToken(partners(allPartners)),
+			partnerIDs:         []string{"anyone"},
+			expectedPartnerIDs: []string{"anyone"},
+		},
+		{
+			description:        "Partners From Token",
+			token:              jwtToken(partners("comcast")),
+			expectedPartnerIDs: []string{"comcast"},
+		},
+		{
+			description:        "Not Allowed",
+			token:              jwtToken(partners("comcast")),
+			partnerIDs:         []string{"comcast", "sky"},
+			expectedPartnerIDs: []string{"comcast", "sky"},
+			expectedErr:        errPartnerNotAllowed,
+		},
+		{
+			description:        "No Partners Claim",
+			token:              jwtToken(map[string]interface{}{}),
+			partnerIDs:         []string{"comcast"},
+			expectedPartnerIDs: []string{"comcast"},
+			expectedErr:        errNoPartners,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			ctx := context.Background()
+			if tc.token != nil {
+				ctx = bascule.WithAuthentication(ctx, bascule.Authentication{Token: tc.token})
+			}
+			msg := wrp.Message{PartnerIDs: tc.partnerIDs}
+			err := authorizePartners(ctx, &msg)
+			if tc.expectedErr != nil {
+				require.NotNil(t, err)
+				assert.Contains(err.Error(), tc.expectedErr.Error())
+			} else {
+				assert.Nil(err)
+			}
+			assert.Equal(tc.expectedPartnerIDs, msg.PartnerIDs)
+		})
+	}
+}
@@ -0,0 +1,392 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/go-kit/kit/log"
+	"github.com/goph/emperror"
+	"github.com/xmidt-org/svalinn/requestParser"
+	"github.com/xmidt-org/webpa-common/v2/logging"
+	"github.com/xmidt-org/wrp-go/v3"
+)
+
+const (
+	defaultKafkaSourceClientID = "svalinn"
+	defaultKafkaMaxInFlight    = 100
+	defaultKafkaRetryInterval  = time.Second
+	defaultKafkaCommitInterval = time.Second
+
+	offsetNewest = "newest"
+	offsetOldest = "oldest"
+)
+
+var (
+	errNoSourceTopics      = errors.New("kafka source has no topics")
+	errNoConsumerGroup     = errors.New("kafka source has no consumer group")
+	errUnknownOffset       = errors.New("unknown kafka initial offset")
+	errInvalidKafkaVersion = errors.New("invalid kafka version")
+)
+
+// KafkaSourceConfig configures consuming events from Kafka, along with or
+// instead of receiving them from webhooks.
+type KafkaSourceConfig struct {
+	// Brokers are the addresses used to find the rest of the cluster.  If
+	// there are none, events aren't consumed from Kafka.
+	Brokers []string
+
+	// Topics are where the WRP messages are consumed from.
+	Topics []string
+
+	// Group is the consumer group whose offsets are committed.
+	Group string
+
+	// ClientID identifies svalinn to the brokers.  Defaults to svalinn.
+	ClientID string
+
+	// Version is the Kafka version the brokers support.
+	Version string
+
+	// InitialOffset is where a partition without a committed offset is
+	// consumed from: newest or oldest.  Defaults to newest.
+	InitialOffset string
+
+	// CommitInterval is how often the offsets are committed.  Defaults to 1s.
+	CommitInterval time.Duration
+
+	// MaxInFlight is how many messages of a partition can be consumed before
+	// they're inserted.  Defaults to 100.
+	MaxInFlight int
+
+	// RetryInterval is how long to wait before trying again when the parsing
+	// queue is full, or before consuming a partition again after a message
+	// couldn't be stored.  Defaults to 1s.
+	RetryInterval time.Duration
+}
+
+// kafkaSource consumes WRP messages from Kafka and parses them.  A message's
+// offset is only committed once its record has been written, or it can never
+// be stored, along with every message before it in its partition.  If a
+// message is shed or its record fails to be written, the partition is
+// consumed again from the last committed offset.
+type kafkaSource struct {
+	group   sarama.ConsumerGroup
+	topics  []string
+	handler *kafkaHandler
+	logger  log.Logger
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newKafkaSource(config KafkaSourceConfig, parser parser, measures *Measures, logger log.Logger) (*kafkaSource, error) {
+	saramaConfig, err := newConsumerConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	group, err := sarama.NewConsumerGroup(config.Brokers, config.Group, saramaConfig)
+	if err != nil {
+		return nil, emperror.WrapWith(err, "failed to create kafka consumer group", "brokers", strings.Join(config.Brokers, ","),
+			"group", config.Group)
+	}
+	return &kafkaSource{
+		group:   group,
+		topics:  config.Topics,
+		handler: newKafkaHandler(config, parser, measures, logger),
+		logger:  logger,
+	}, nil
+}
+
+func newConsumerConfig(config KafkaSourceConfig) (*sarama.Config, error) {
+	switch {
+	case len(config.Topics) == 0:
+		return nil, errNoSourceTopics
+	case config.Group == "":
+		return nil, errNoConsumerGroup
+	}
+
+	c := sarama.NewConfig()
+	c.ClientID = defaultKafkaSourceClientID
+	if config.ClientID != "" {
+		c.ClientID = config.ClientID
+	}
+	if config.Version != "" {
+		version, err := sarama.ParseKafkaVersion(config.Version)
+		if err != nil {
+			return nil, emperror.WrapWith(errInvalidKafkaVersion, err.Error(), "version", config.Version)
+		}
+		c.Version = version
+	}
+	switch strings.ToLower(config.InitialOffset) {
+	case "", offsetNewest:
+		c.Consumer.Offsets.Initial = sarama.OffsetNewest
+	case offsetOldest:
+		c.Consumer.Offsets.Initial = sarama.OffsetOldest
+	default:
+		return nil, emperror.With(errUnknownOffset, "offset", config.InitialOffset)
+	}
+	// only the offsets of inserted messages are marked, so committing them
+	// automatically never skips a message.
+	c.Consumer.Offsets.AutoCommit.Enable = true
+	c.Consumer.Offsets.AutoCommit.Interval = defaultKafkaCommitInterval
+	if config.CommitInterval > 0 {
+		c.Consumer.Offsets.AutoCommit.Interval = config.CommitInterval
+	}
+	c.Consumer.Return.Errors = true
+
+	if err := c.Validate(); err != nil {
+		return nil, emperror.Wrap(err, "invalid kafka config")
+	}
+	return c, nil
+}
+
+// Start consumes the topics until Stop is called, joining the group again
+// whenever it's rebalanced.
+func (k *kafkaSource) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	k.cancel = cancel
+	k.wg.Add(2)
+	go func() {
+		defer k.wg.Done()
+		for ctx.Err() == nil {
+			if err := k.group.Consume(ctx, k.topics, k.handler); err != nil {
+				logging.Error(k.logger, emperror.Context(err)...).Log(logging.MessageKey(), "Failed to consume from kafka",
+					logging.ErrorKey(), err.Error())
+				select {
+				case <-ctx.Done():
+				case <-time.After(k.handler.retryInterval):
+				}
+			}
+		}
+	}()
+	go func() {
+		defer k.wg.Done()
+		for err := range k.group.Errors() {
+			logging.Error(k.logger, emperror.Context(err)...).Log(logging.MessageKey(), "Kafka consumer error",
+				logging.ErrorKey(), err.Error())
+		}
+	}()
+}
+
+// Stop waits for the messages being consumed to be inserted, commits their
+// offsets, and leaves the group.
+func (k *kafkaSource) Stop() error {
+	if k.cancel != nil {
+		k.cancel()
+	}
+	err := k.group.Close()
+	k.wg.Wait()
+	return err
+}
+
+// kafkaHandler parses the messages of each partition claimed by the group.
+type kafkaHandler struct {
+	parser        parser
+	measures      *Measures
+	logger        log.Logger
+	maxInFlight   int
+	retryInterval time.Duration
+}
+
+func newKafkaHandler(config KafkaSourceConfig, parser parser, measures *Measures, logger log.Logger) *kafkaHandler {
+	h := &kafkaHandler{
+		parser:        parser,
+		measures:      measures,
+		logger:        logger,
+		maxInFlight:   config.MaxInFlight,
+		retryInterval: config.RetryInterval,
+	}
+	if h.maxInFlight <= 0 {
+		h.maxInFlight = defaultKafkaMaxInFlight
+	}
+	if h.retryInterval <= 0 {
+		h.retryInterval = defaultKafkaRetryInterval
+	}
+	return h
+}
+
+func (h *kafkaHandler) Setup(sarama.ConsumerGroupSession) error   { return nil }
+func (h *kafkaHandler) Cleanup(sarama.ConsumerGroupSession) error { return nil }
+
+// ConsumeClaim parses the partition's messages in order, marking the offset
+// after the last message whose record has been written, once the messages
+// before it have been too.  When the claim ends, it waits for the messages
+// already parsed before returning, so their offsets are committed.  If a
+// message is shed or its record fails to be written, no offset past it is
+// marked and the claim ends, which ends the session, so the partition is
+// consumed again from that message once the group rejoins.
+func (h *kafkaHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	var (
+		ctx      = session.Context()
+		offsets  = new(offsetTracker)
+		inFlight = make(chan struct{}, h.maxInFlight)
+		failed   = make(chan struct{})
+		fail     sync.Once
+		wg       sync.WaitGroup
+	)
+	defer func() {
+		wg.Wait()
+		select {
+		case <-failed:
+			// give whatever failed a chance to recover before the messages
+			// are consumed again.
+			select {
+			case <-ctx.Done():
+			case <-time.After(h.retryInterval):
+			}
+		default:
+		}
+	}()
+	for {
+		var (
+			msg *sarama.ConsumerMessage
+			ok  bool
+		)
+		select {
+		case msg, ok = <-claim.Messages():
+		case <-ctx.Done():
+		case <-failed:
+		}
+		if !ok {
+			return nil
+		}
+		select {
+		case inFlight <- struct{}{}:
+		case <-ctx.Done():
+			return nil
+		case <-failed:
+			return nil
+		}
+		wg.Add(1)
+		finish := func(outcome string) {
+			h.count(outcome)
+			if outcome == failedOutcome {
+				logging.Warn(h.logger).Log(logging.MessageKey(), "Kafka message couldn't be stored, consuming the partition again",
+					"topic", msg.Topic, "partition", msg.Partition, "offset", msg.Offset)
+				fail.Do(func() { close(failed) })
+			} else if next, ok := offsets.finish(msg.Offset); ok {
+				session.MarkOffset(msg.Topic, msg.Partition, next, "")
+			}
+			<-inFlight
+			wg.Done()
+		}
+		offsets.add(msg.Offset)
+		if !h.parse(ctx, msg, finish) {
+			// the session is over, so the message will be consumed again.
+			<-inFlight
+			wg.Done()
+			return nil
+		}
+	}
+}
+
+// parse decodes the message and queues it to be parsed, waiting for room in
+// the queue.  It returns false if the session ends before the message is
+// queued.
+func (h *kafkaHandler) parse(ctx context.Context, msg *sarama.ConsumerMessage, finish func(string)) bool {
+	var message wrp.Message
+	if err := wrp.NewDecoderBytes(msg.Value, wrp.Msgpack).Decode(&message); err != nil {
+		logging.Error(h.logger).Log(logging.MessageKey(), "Could not decode kafka message", logging.ErrorKey(), err.Error(),
+			"topic", msg.Topic, "partition", msg.Partition, "offset", msg.Offset)
+		// the message will never decode, so it's skipped.
+		finish(invalidOutcome)
+		return true
+	}
+	w := requestParser.WrpWithTime{
+		Message:   message,
+		Beginning: time.Now(),
+		Done: func(err error) {
+			switch err {
+			case nil:
+				finish(storedOutcome)
+			case requestParser.ErrBlacklisted:
+				finish(blacklistedOutcome)
+			case requestParser.ErrQueueFull, requestParser.ErrInsertFailed:
+				// the message could be stored if it's tried again.
+				finish(failedOutcome)
+			default:
+				// the message is invalid, so it will never be stored.
+				finish(droppedOutcome)
+			}
+		},
+	}
+	for {
+		err := h.parser.Parse(w)
+		if err == nil {
+			return true
+		}
+		h.count(rejectedOutcome)
+		logging.Warn(h.logger).Log(logging.MessageKey(), "Kafka message wasn't accepted, trying again", logging.ErrorKey(), err.Error(),
+			"topic", msg.Topic, "partition", msg.Partition, "offset", msg.Offset)
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(h.retryInterval):
+		}
+	}
+}
+
+func (h *kafkaHandler) count(outcome string) {
+	if h.measures != nil {
+		h.measures.KafkaMessages.With(outcomeLabel, outcome).Add(1.0)
+	}
+}
+
+// offsetTracker finds the offset to commit for a partition whose messages
+// finish out of order: the one after the last message that finished once
+// every message before it has.
+type offsetTracker struct {
+	lock     sync.Mutex
+	pending  []int64
+	finished map[int64]bool
+}
+
+// add notes a message that hasn't finished.  Messages must be added in
+// order.
+func (o *offsetTracker) add(offset int64) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.pending = append(o.pending, offset)
+}
+
+// finish notes a finished message, returning the offset to commit if it's
+// changed.
+func (o *offsetTracker) finish(offset int64) (int64, bool) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	if o.finished == nil {
+		o.finished = make(map[int64]bool)
+	}
+	o.finished[offset] = true
+	var (
+		next  int64
+		moved bool
+	)
+	for len(o.pending) > 0 && o.finished[o.pending[0]] {
+		delete(o.finished, o.pending[0])
+		next, moved = o.pending[0]+1, true
+		o.pending = o.pending[1:]
+	}
+	return next, moved
+}
@@ -0,0 +1,301 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/svalinn/requestParser"
+	"github.com/xmidt-org/webpa-common/v2/logging"
+	"github.com/xmidt-org/webpa-common/v2/xmetrics/xmetricstest"
+	"github.com/xmidt-org/wrp-go/v3"
+)
+
+func TestNewConsumerConfig(t *testing.T) {
+	tests := []struct {
+		description    string
+		config         KafkaSourceConfig
+		expectedOffset int64
+		expectedErr    error
+	}{
+		{
+			description:    "Success",
+			config:         KafkaSourceConfig{Topics: []string{"events"}, Group: "svalinn"},
+			expectedOffset: sarama.OffsetNewest,
+		},
+		{
+			description:    "Oldest",
+			config:         KafkaSourceConfig{Topics: []string{"events"}, Group: "svalinn", InitialOffset: "Oldest", Version: "2.8.0"},
+			expectedOffset: sarama.OffsetOldest,
+		},
+		{
+			description: "No Topics",
+			config:      KafkaSourceConfig{Group: "svalinn"},
+			expectedErr: errNoSourceTopics,
+		},
+		{
+			description: "No Group",
+			config:      KafkaSourceConfig{Topics: []string{"events"}},
+			expectedErr: errNoConsumerGroup,
+		},
+		{
+			description: "Unknown Offset",
+			config:      KafkaSourceConfig{Topics: []string{"events"}, Group: "svalinn", InitialOffset: "middle"},
+			expectedErr: errUnknownOffset,
+		},
+		{
+			description: "Invalid Version",
+			config:      KafkaSourceConfig{Topics: []string{"events"}, Group: "svalinn", Version: "latest"},
+			expectedErr: errInvalidKafkaVersion,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			c, err := newConsumerConfig(tc.config)
+			if tc.expectedErr != nil {
+				require.NotNil(t, err)
+				assert.Contains(t, err.Error(), tc.expectedErr.Error())
+				return
+			}
+			require.Nil(t, err)
+			assert.Equal(t, tc.expectedOffset, c.Consumer.Offsets.Initial)
+			assert.True(t, c.Consumer.Offsets.AutoCommit.Enable)
+		})
+	}
+}
+
+func TestOffsetTracker(t *testing.T) {
+	assert := assert.New(t)
+	o := new(offsetTracker)
+	for _, offset := range []int64{3, 4, 7, 8} {
+		o.add(offset)
+	}
+	_, moved := o.finish(4)
+	assert.False(moved)
+	next, moved := o.finish(3)
+	assert.True(moved)
+	assert.Equal(int64(5), next)
+	_, moved = o.finish(8)
+	assert.False(moved)
+	next, moved = o.finish(7)
+	assert.True(moved)
+	assert.Equal(int64(9), next)
+}
+
+func TestKafkaHandlerConsumeClaim(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	value := func(destination string) []byte {
+		var b []byte
+		require.Nil(wrp.NewEncoderBytes(&b, wrp.Msgpack).Encode(&wrp.Message{
+			Type:        wrp.SimpleEventMessageType,
+			Source:      "mac:112233445566",
+			Destination: destination,
+		}))
+		return b
+	}
+	claim := &mockClaim{messages: make(chan *sarama.ConsumerMessage, 4)}
+	claim.messages <- &sarama.ConsumerMessage{Topic: "events", Offset: 10, Value: value("event:device-status/mac:112233445566/online")}
+	claim.messages <- &sarama.ConsumerMessage{Topic: "events", Offset: 11, Value: value("event:device-status/mac:112233445566/offline")}
+	claim.messages <- &sarama.ConsumerMessage{Topic: "events", Offset: 12, Value: value("event:device-status/mac:112233445566/online")}
+	claim.messages <- &sarama.ConsumerMessage{Topic: "events", Offset: 13, Value: []byte("not msgpack")}
+	close(claim.messages)
+
+	parsed := make(chan requestParser.WrpWithTime, 3)
+	parser := new(mockParser)
+	parser.On("Parse", mock.Anything).Return(requestParser.ErrQueueFull).Once()
+	parser.On("Parse", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		parsed <- args.Get(0).(requestParser.WrpWithTime)
+	}).Times(3)
+
+	session := &mockSession{ctx: context.Background()}
+	session.On("MarkOffset", "events", int32(0), int64(11), "").Once()
+	session.On("MarkOffset", "events", int32(0), int64(14), "").Once()
+
+	p := xmetricstest.NewProvider(nil, Metrics)
+	h := newKafkaHandler(KafkaSourceConfig{RetryInterval: time.Millisecond}, parser, NewMeasures(p), logging.NewTestLogger(nil, t))
+	done := make(chan error)
+	go func() {
+		done <- h.ConsumeClaim(session, claim)
+	}()
+
+	var events []requestParser.WrpWithTime
+	for i := 0; i < 3; i++ {
+		select {
+		case w := <-parsed:
+			events = append(events, w)
+		case <-time.After(time.Second):
+			require.FailNow("message wasn't parsed")
+		}
+	}
+	// the claim waits for the parsed messages to finish.
+	select {
+	case <-done:
+		require.FailNow("claim ended before its messages finished")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	events[2].Done(nil)
+	events[0].Done(nil)
+	events[1].Done(requestParser.ErrBlacklisted)
+	select {
+	case err := <-done:
+		assert.Nil(err)
+	case <-time.After(time.Second):
+		require.FailNow("claim didn't end")
+	}
+
+	parser.AssertExpectations(t)
+	session.AssertExpectations(t)
+	p.Assert(t, KafkaSourceCounter, outcomeLabel, storedOutcome)(xmetricstest.Value(2.0))
+	p.Assert(t, KafkaSourceCounter, outcomeLabel, blacklistedOutcome)(xmetricstest.Value(1.0))
+	p.Assert(t, KafkaSourceCounter, outcomeLabel, invalidOutcome)(xmetricstest.Value(1.0))
+	p.Assert(t, KafkaSourceCounter, outcomeLabel, rejectedOutcome)(xmetricstest.Value(1.0))
+}
+
+func TestKafkaHandlerFailedMessage(t *testing.T) {
+	tests := []struct {
+		description     string
+		err             error
+		expectedOutcome string
+		expectedOffset  int64
+	}{
+		{
+			description:     "Invalid Record",
+			err:             errors.New("deathdate has passed"),
+			expectedOutcome: droppedOutcome,
+			expectedOffset:  14,
+		},
+		{
+			description:     "Shed",
+			err:             requestParser.ErrQueueFull,
+			expectedOutcome: failedOutcome,
+			expectedOffset:  11,
+		},
+		{
+			description:     "Insert Failed",
+			err:             requestParser.ErrInsertFailed,
+			expectedOutcome: failedOutcome,
+			expectedOffset:  11,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			var b []byte
+			require.Nil(wrp.NewEncoderBytes(&b, wrp.Msgpack).Encode(&wrp.Message{Type: wrp.SimpleEventMessageType}))
+			// the claim isn't closed, so it only ends if a message fails.
+			claim := &mockClaim{messages: make(chan *sarama.ConsumerMessage, 4)}
+			for offset := int64(10); offset < 14; offset++ {
+				claim.messages <- &sarama.ConsumerMessage{Topic: "events", Offset: offset, Value: b}
+			}
+
+			parsed := make(chan requestParser.WrpWithTime, 4)
+			parser := new(mockParser)
+			parser.On("Parse", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+				parsed <- args.Get(0).(requestParser.WrpWithTime)
+			})
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			session := &mockSession{ctx: ctx}
+			session.On("MarkOffset", "events", int32(0), mock.Anything, "")
+
+			p := xmetricstest.NewProvider(nil, Metrics)
+			h := newKafkaHandler(KafkaSourceConfig{RetryInterval: time.Millisecond}, parser, NewMeasures(p), logging.NewTestLogger(nil, t))
+			done := make(chan error)
+			go func() {
+				done <- h.ConsumeClaim(session, claim)
+			}()
+
+			var events []requestParser.WrpWithTime
+			for i := 0; i < 4; i++ {
+				select {
+				case w := <-parsed:
+					events = append(events, w)
+				case <-time.After(time.Second):
+					require.FailNow("message wasn't parsed")
+				}
+			}
+			events[0].Done(nil)
+			events[1].Done(tc.err)
+			events[3].Done(nil)
+			events[2].Done(nil)
+
+			if tc.expectedOutcome == failedOutcome {
+				select {
+				case err := <-done:
+					assert.Nil(err)
+				case <-time.After(time.Second):
+					require.FailNow("claim didn't end")
+				}
+			} else {
+				cancel()
+				<-done
+			}
+			// the offset is never marked past a message that failed, so it's
+			// consumed again.
+			offsets := make([]int64, 0)
+			for _, call := range session.Calls {
+				offsets = append(offsets, call.Arguments.Get(2).(int64))
+			}
+			assert.Equal(tc.expectedOffset, offsets[len(offsets)-1])
+			for _, offset := range offsets {
+				assert.True(offset <= tc.expectedOffset)
+			}
+			p.Assert(t, KafkaSourceCounter, outcomeLabel, tc.expectedOutcome)(xmetricstest.Value(1.0))
+			p.Assert(t, KafkaSourceCounter, outcomeLabel, storedOutcome)(xmetricstest.Value(3.0))
+		})
+	}
+}
+
+func TestKafkaHandlerSessionEnds(t *testing.T) {
+	claim := &mockClaim{messages: make(chan *sarama.ConsumerMessage, 1)}
+	var b []byte
+	require.Nil(t, wrp.NewEncoderBytes(&b, wrp.Msgpack).Encode(&wrp.Message{Type: wrp.SimpleEventMessageType}))
+	claim.messages <- &sarama.ConsumerMessage{Topic: "events", Offset: 10, Value: b}
+
+	parser := new(mockParser)
+	parser.On("Parse", mock.Anything).Return(requestParser.ErrQueueFull)
+	ctx, cancel := context.WithCancel(context.Background())
+	session := &mockSession{ctx: ctx}
+
+	h := newKafkaHandler(KafkaSourceConfig{RetryInterval: time.Millisecond}, parser, nil, logging.NewTestLogger(nil, t))
+	done := make(chan error)
+	go func() {
+		done <- h.ConsumeClaim(session, claim)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-done:
+		assert.Nil(t, err)
+	case <-time.After(time.Second):
+		require.FailNow(t, "claim didn't end")
+	}
+	// the message was never accepted, so its offset isn't marked.
+	session.AssertNotCalled(t, "MarkOffset", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -19,7 +19,6 @@ package main
 
 import (
 	"context"
-	"crypto/sha1"
 	"fmt"
 	"io"
 	olog "log"
@@ -42,7 +41,6 @@ import (
 	"github.com/spf13/viper"
 
 	"github.com/xmidt-org/bascule/acquire"
-	"github.com/xmidt-org/bascule/basculehttp"
 	db "github.com/xmidt-org/codex-db"
 	"github.com/xmidt-org/codex-db/batchInserter"
 	"github.com/xmidt-org/codex-db/blacklist"
@@ -50,6 +48,7 @@ import (
 	"github.com/xmidt-org/codex-db/healthlogger"
 	dbretry "github.com/xmidt-org/codex-db/retry"
 	"github.com/xmidt-org/svalinn/requestParser"
+	"github.com/xmidt-org/svalinn/sink"
 	"github.com/xmidt-org/voynicrypto"
 	"github.com/xmidt-org/webpa-common/v2/basculechecks"
 	"github.com/xmidt-org/webpa-common/v2/basculemetrics"
@@ -58,7 +57,6 @@ import (
 	"github.com/xmidt-org/webpa-common/v2/server"
 	"github.com/xmidt-org/webpa-common/v2/xmetrics"
 	webhook "github.com/xmidt-org/wrp-listener"
-	"github.com/xmidt-org/wrp-listener/hashTokenFactory"
 	secretGetter "github.com/xmidt-org/wrp-listener/secret"
 	"github.com/xmidt-org/wrp-listener/webhookClient"
 )
@@ -74,15 +72,27 @@ var (
 )
 
 type SvalinnConfig struct {
-	Endpoint          string
-	Health            HealthConfig
-	Webhook           WebhookConfig
-	Secret            SecretConfig
-	RequestParser     requestParser.Config
-	BatchInserter     batchInserter.Config
-	Db                cassandra.Config
-	InsertRetries     backoff.ExponentialBackOff
-	BlacklistInterval time.Duration
+	Endpoint            string
+	Health              HealthConfig
+	Webhook             WebhookConfig
+	Secret              SecretConfig
+	Auth                AuthConfig
+	JWT                 JWTConfig
+	TLS                 TLSConfig
+	Admin               AdminConfig
+	RequestParser       requestParser.Config
+	BatchInserter       batchInserter.Config
+	PriorityInserter    PriorityBatchInserterConfig
+	Db                  DbConfig
+	Sinks               []SinkConfig
+	Targets             []TargetConfig
+	KafkaSource         KafkaSourceConfig
+	GRPC                GRPCConfig
+	InsertRetries       backoff.ExponentialBackOff
+	BlacklistInterval   time.Duration
+	LoadShedding        LoadSheddingConfig
+	MaxRequestSize      int64
+	MaxDecompressedSize int64
 }
 
 type WebhookConfig struct {
@@ -93,11 +103,30 @@ type WebhookConfig struct {
 	Request              webhook.W
 	JWT                  acquire.RemoteBearerTokenAcquirerOptions
 	Basic                string
+	Acquirer             AcquirerConfig
+	Retry                RegistrationRetryConfig
+	Registrations        []RegistrationConfig
+	Unregister           UnregisterConfig
+
+	// InstanceURL adds this instance's id to the callback urls, so each
+	// instance registers its own webhooks.
+	InstanceURL bool
+
+	// InstanceID is the id added to the callback urls.  If empty, the
+	// hostname is used.
+	InstanceID string
+
+	// DeriveEvents registers the events matched by the rules instead of the
+	// events configured in each request.
+	DeriveEvents bool
 }
 
 type SecretConfig struct {
 	Header    string
 	Delimiter string
+	Algorithm string
+	Rotation  SecretRotationConfig
+	Replay    ReplayConfig
 }
 
 type Svalinn struct {
@@ -105,12 +134,21 @@ type Svalinn struct {
 	shutdown      chan struct{}
 	waitGroup     *sync.WaitGroup
 	requestParser *requestParser.RequestParser
+	app           *App
 	batchInserter *batchInserter.BatchInserter
-	registerer    *webhookClient.PeriodicRegisterer
+	priorityBatch *batchInserter.BatchInserter
+	targets       targets
+	kafkaSource   *kafkaSource
+	registerers   registrationSupervisors
+	tlsServer     *tlsServer
+	grpcServer    *grpcServer
+	adminServer   *http.Server
 }
 
 type database struct {
+	memory             *memoryDb
 	dbClose            func() error
+	sinksClose         func() error
 	blacklistStop      chan struct{}
 	blacklistRefresher blacklist.List
 	inserter           db.Inserter
@@ -143,7 +181,7 @@ func svalinn(arguments []string) {
 
 	var (
 		f, v                                = pflag.NewFlagSet(applicationName, pflag.ContinueOnError), viper.New()
-		logger, metricsRegistry, codex, err = server.Initialize(applicationName, arguments, f, v, cassandra.Metrics, dbretry.Metrics, requestParser.Metrics, batchInserter.Metrics, basculechecks.Metrics, webhookClient.Metrics, basculemetrics.Metrics, Metrics)
+		logger, metricsRegistry, codex, err = server.Initialize(applicationName, arguments, f, v, cassandra.Metrics, dbretry.Metrics, requestParser.Metrics, batchInserter.Metrics, basculechecks.Metrics, webhookClient.Metrics, basculemetrics.Metrics, sink.Metrics, Metrics)
 	)
 
 	if parseErr, done := printVersion(f, arguments); done {
@@ -159,18 +197,20 @@ func svalinn(arguments []string) {
 	config := new(SvalinnConfig)
 	v.Unmarshal(config)
 
-	if config.Webhook.Request.Config.URL == "" {
-		config.Webhook.Request.Config.URL = codex.Server
+	registrations, err := newRegistrations(config, codex.Server)
+	exitIfError(logger, emperror.Wrap(err, "invalid webhook registrations"))
+	checkRules(logger, registrations)
+	exitIfError(logger, emperror.Wrap(validateTargets(config), "invalid storage targets"))
+
+	if config.Secret.Algorithm == "" {
+		config.Secret.Algorithm = defaultSignatureAlgorithm
 	}
-	config.Webhook.Request.Config.URL = config.Webhook.Request.Config.URL + apiBase + config.Endpoint
 
 	cipherOptions, err := voynicrypto.FromViper(v)
 	exitIfError(logger, emperror.Wrap(err, "failed to initialize cipher options"))
 	encrypter, err := cipherOptions.GetEncrypter(logger)
 	exitIfError(logger, emperror.Wrap(err, "failed to load cipher encrypter"))
 
-	secretGetter := secretGetter.NewConstantSecret(config.Webhook.Request.Config.Secret)
-
 	var m *basculemetrics.AuthValidationMeasures
 
 	if metricsRegistry != nil {
@@ -178,83 +218,235 @@ func svalinn(arguments []string) {
 	}
 	listener := basculemetrics.NewMetricListener(m)
 
-	svalinnHandler := alice.New()
-
-	if config.Secret.Header != "" && config.Webhook.Request.Config.Secret != "" {
-		htf, err := hashTokenFactory.New("Sha1", sha1.New, secretGetter)
-		exitIfError(logger, emperror.Wrap(err, "failed to create hashTokenFactory"))
-
-		authConstructor := basculehttp.NewConstructor(
-			basculehttp.WithCLogger(GetLogger),
-			basculehttp.WithTokenFactory("Sha1", htf),
-			basculehttp.WithHeaderName(config.Secret.Header),
-			basculehttp.WithHeaderDelimiter(config.Secret.Delimiter),
-			basculehttp.WithCErrorResponseFunc(listener.OnErrorResponse),
-		)
-
-		svalinnHandler = alice.New(SetLogger(logger), authConstructor, basculehttp.NewListenerDecorator(listener))
+	svalinnMeasures := NewMeasures(metricsRegistry)
+	mode := deriveAuthMode(config)
+	if config.Auth.Mode != "" {
+		mode, err = parseAuthMode(config.Auth.Mode)
+		exitIfError(logger, emperror.Wrap(err, "failed to parse auth mode"))
+	} else {
+		logging.Warn(logger).Log(logging.MessageKey(), "auth.mode isn't set, using the mode the secret and jwt config imply", "mode", mode.String())
+		if !mode.signature && (config.Secret.Header != "" || config.Webhook.Request.Config.Secret != "") {
+			// the sender only signs events when it's registered with a secret, so
+			// both are needed to validate signatures.
+			logging.Warn(logger).Log(logging.MessageKey(), "Request signatures aren't validated: secret.header and webhook.request.config.secret must both be set",
+				"header", config.Secret.Header)
+		}
 	}
+	exitIfError(logger, emperror.Wrap(mode.validate(config), "invalid auth config"))
+	logAuthMode(logger, mode, config)
+
 	router := mux.NewRouter()
 
 	database, err := setupDb(config, logger, metricsRegistry)
 	exitIfError(logger, emperror.Wrap(err, "failed to initialize database connection"))
+	if database.memory != nil {
+		logging.Warn(logger).Log(logging.MessageKey(), "Inserting records into memory, they won't be persisted",
+			"maxRecords", database.memory.maxRecords, "adminAddress", config.Admin.Address)
+	}
 
 	s := &Svalinn{}
-	svalinnMeasures := NewMeasures(metricsRegistry)
-	s.batchInserter, err = batchInserter.NewBatchInserter(config.BatchInserter, logger, metricsRegistry, database.inserter, svalinnMeasures)
+	// the records are tracked until they're written, so the kafka offsets
+	// are only committed once the database has the records.
+	tracker := newInsertTracker()
+	s.batchInserter, err = batchInserter.NewBatchInserter(config.BatchInserter, logger, metricsRegistry, tracker.wrap(database.inserter), svalinnMeasures)
 	exitIfError(logger, emperror.Wrap(err, "failed to create batch inserter"))
 
-	s.requestParser, err = requestParser.NewRequestParser(config.RequestParser, logger, metricsRegistry, s.batchInserter, database.blacklistRefresher, encrypter, svalinnMeasures)
+	var inserter recordInserter = s.batchInserter
+	if config.PriorityInserter.Enabled {
+		s.priorityBatch, err = batchInserter.NewBatchInserter(config.PriorityInserter.BatchInserter, logger, metricsRegistry, tracker.wrap(database.inserter), svalinnMeasures)
+		exitIfError(logger, emperror.Wrap(err, "failed to create priority batch inserter"))
+		inserter = &priorityInserter{inserter: s.batchInserter, highPriority: s.priorityBatch}
+	}
+	if len(config.Targets) > 0 {
+		s.targets, err = newTargets(config.Targets, sinkOptions{
+			metricsRegistry: metricsRegistry,
+			health:          database.health,
+			logger:          logger,
+		}, svalinnMeasures, tracker)
+		exitIfError(logger, emperror.Wrap(err, "failed to create storage targets"))
+	}
+	inserter = newTargetRouter(inserter, s.targets, tracker)
+
+	s.requestParser, err = requestParser.NewRequestParser(config.RequestParser, logger, metricsRegistry, inserter, database.blacklistRefresher, encrypter, svalinnMeasures)
 	exitIfError(logger, emperror.Wrap(err, "failed to create request parser"))
 
-	app := &App{
-		logger: logger,
-		parser: s.requestParser,
+	if len(config.KafkaSource.Brokers) > 0 {
+		s.kafkaSource, err = newKafkaSource(config.KafkaSource, s.requestParser, svalinnMeasures, logger)
+		exitIfError(logger, emperror.Wrap(err, "failed to create kafka source"))
+	}
+
+	s.app = &App{
+		logger:              logger,
+		parser:              s.requestParser,
+		timeTracker:         svalinnMeasures,
+		sizeTracker:         svalinnMeasures,
+		health:              ingestionHealth{health: database.health},
+		loadShedding:        config.LoadShedding,
+		maxRequestSize:      config.MaxRequestSize,
+		maxDecompressedSize: config.MaxDecompressedSize,
 	}
 
 	// MARK: Actual server logic
-	router.Handle(apiBase+config.Endpoint, svalinnHandler.ThenFunc(app.handleWebhook))
+	maxRequestSize := config.MaxRequestSize
+	if maxRequestSize <= 0 {
+		maxRequestSize = defaultMaxRequestSize
+	}
+	for _, r := range registrations {
+		authChain, err := newAuthChain(mode, config, r.secret(), r.rotation, svalinnMeasures, listener, logger)
+		exitIfError(logger, emperror.WrapWith(err, "failed to create auth", "registration", r.name))
+		// limit the body before anything else reads it, including the auth check.
+		svalinnHandler := alice.New(LimitRequestBody(maxRequestSize)).Extend(authChain)
+		router.Handle(apiBase+r.endpoint, svalinnHandler.Then(s.app.webhookHandler(r.rules)))
+		logging.Info(logger).Log(logging.MessageKey(), "Serving webhook endpoint", "registration", r.name,
+			"endpoint", apiBase+r.endpoint, "events", r.request.Events, "ownRules", r.rules != nil,
+			"previousSecretAccepted", r.rotation.PreviousSecret != "", "previousSecretExpires", r.rotation.PreviousSecretExpires)
+	}
+	if config.GRPC.Address != "" {
+		// calls are signed with the same secret as the webhook endpoint.
+		authChain, err := newAuthChain(mode, config, config.Webhook.Request.Config.Secret, config.Secret.Rotation, svalinnMeasures, listener, logger)
+		exitIfError(logger, emperror.Wrap(err, "failed to create gRPC auth"))
+		// a stream's messages aren't signed, so streams are only authenticated
+		// with the other modes, and can't be opened when signatures are all
+		// calls can be authenticated with.
+		var streamChain *alice.Chain
+		if streamMode := mode.withoutSignature(); !mode.signature || !streamMode.isNone() {
+			chain, err := newAuthChain(streamMode, config, "", SecretRotationConfig{}, svalinnMeasures, listener, logger)
+			exitIfError(logger, emperror.Wrap(err, "failed to create gRPC stream auth"))
+			streamChain = &chain
+		} else {
+			logging.Warn(logger).Log(logging.MessageKey(), "only signatures authenticate calls, so gRPC streams will be rejected")
+		}
+		s.grpcServer, err = newGRPCServer(config.GRPC, config.TLS, s.app, authChain, streamChain, logger)
+		exitIfError(logger, emperror.Wrap(err, "failed to create gRPC server"))
+	}
 	s.requestParser.Start()
 	s.batchInserter.Start()
-	startHealth(logger, database.health, config)
+	if s.priorityBatch != nil {
+		s.priorityBatch.Start()
+	}
+	s.targets.Start()
+	if s.kafkaSource != nil {
+		s.kafkaSource.Start()
+	}
+	if s.grpcServer != nil {
+		s.grpcServer.Start()
+		logging.Info(logger).Log(logging.MessageKey(), "Serving gRPC ingestion service", "address", config.GRPC.Address,
+			"tls", config.GRPC.TLS)
+	}
 	// if the register interval is 0 and these values aren't set, don't register
-	if config.Webhook.RegistrationInterval > 0 && config.Webhook.RegistrationURL != "" && config.Webhook.Request.Config.URL != "" && len(config.Webhook.Request.Events) > 0 {
-		acquirer, err := determineTokenAcquirer(config.Webhook)
-		if err != nil {
-			logging.Error(logger, emperror.Context(err)...).Log(logging.MessageKey(), "Failed to determine token acquirer", logging.ErrorKey(), err.Error())
-			// TODO: we shouldn't continue trying to set the webhook registerer up if we fail
-		}
-		basicConfig := webhookClient.BasicConfig{
-			Timeout:         config.Webhook.Timeout,
-			RegistrationURL: config.Webhook.RegistrationURL,
-			Request:         config.Webhook.Request,
+	if config.Webhook.RegistrationInterval > 0 && config.Webhook.RegistrationURL != "" {
+		acquirer, err := determineTokenAcquirer(config.Webhook, logger)
+		exitIfError(logger, emperror.Wrap(err, "failed to determine token acquirer"))
+		measures := webhookClient.NewMeasures(metricsRegistry)
+		for _, r := range registrations {
+			if !r.shouldRegister() {
+				continue
+			}
+			responses := newResponseRecorder(nil)
+			basicConfig := webhookClient.BasicConfig{
+				Timeout:         config.Webhook.Timeout,
+				ClientTransport: responses,
+				RegistrationURL: config.Webhook.RegistrationURL,
+				Request:         r.request,
+			}
+			secret := secretGetter.NewConstantSecret(r.secret())
+			registerer, err := webhookClient.NewBasicRegisterer(acquirer, secret, basicConfig)
+			exitIfError(logger, emperror.WrapWith(err, "failed to create basic registerer", "registration", r.name))
+			basicConfig.ClientTransport = nil
+			unregisterer, err := newUnregisterer(config.Webhook.Unregister, acquirer, secret, basicConfig)
+			exitIfError(logger, emperror.WrapWith(err, "failed to create unregisterer", "registration", r.name))
+			supervisor, err := newRegistrationSupervisor(supervisorConfig{
+				name:         r.name,
+				request:      r.request,
+				registerer:   registerer,
+				unregisterer: unregisterer,
+				responses:    responses,
+				interval:     config.Webhook.RegistrationInterval,
+				retry:        config.Webhook.Retry,
+				measures:     measures,
+				sinceSuccess: svalinnMeasures.SinceRegistered.With(registrationLabel, r.name),
+				logger:       log.With(logger, "registration", r.name),
+			})
+			exitIfError(logger, emperror.WrapWith(err, "failed to create registration supervisor", "registration", r.name))
+			err = database.health.AddCheck(&health.Config{
+				Name:     registrationCheckName + "-" + r.name,
+				Checker:  supervisor,
+				Interval: registrationCheckInterval,
+				Fatal:    true,
+			})
+			exitIfError(logger, emperror.WrapWith(err, "failed to add registration health check", "registration", r.name))
+			s.registerers = append(s.registerers, supervisor)
 		}
-		registerer, err := webhookClient.NewBasicRegisterer(acquirer, secretGetter, basicConfig)
-		if err != nil {
-			logging.Error(logger, emperror.Context(err)...).Log(logging.MessageKey(), "Failed to create basic registerer", logging.ErrorKey(), err.Error())
-			// TODO: we shouldn't continue trying to set the webhook registerer up if we fail
+		if len(s.registerers) > 0 && s.registerers[0].unregisterer != nil && !config.Webhook.InstanceURL {
+			logging.Warn(logger).Log(logging.MessageKey(), "Unregistering on shutdown without webhook.instanceURL removes the registrations for every instance sharing the callback url")
 		}
-		periodicRegisterer, err := webhookClient.NewPeriodicRegisterer(registerer, config.Webhook.RegistrationInterval, logger, webhookClient.NewMeasures(metricsRegistry))
-		if err != nil {
-			logging.Error(logger, emperror.Context(err)...).Log(logging.MessageKey(), "Failed to create periodic registerer", logging.ErrorKey(), err.Error())
+		s.registerers.Start()
+	}
+	if config.Admin.Address != "" {
+		s.adminServer = &http.Server{
+			Addr:              config.Admin.Address,
+			Handler:           newAdminHandler(s.registerers, database.memory, logger),
+			ReadHeaderTimeout: codex.Primary.ReadHeaderTimeout,
 		}
-
-		s.registerer = periodicRegisterer
-		periodicRegisterer.Start()
+		go func() {
+			err := s.adminServer.ListenAndServe()
+			if err != nil && err != http.ErrServerClosed {
+				logging.Error(logger).Log(logging.MessageKey(), "admin server exited", logging.ErrorKey(), err.Error())
+			}
+		}()
+		logging.Info(logger).Log(logging.MessageKey(), "Serving admin endpoints", "address", config.Admin.Address)
 	}
+	startHealth(logger, database.health, config)
 
 	// MARK: Starting the server
+	var primaryHandler http.Handler = router
+	if config.TLS.Address != "" {
+		s.tlsServer, err = newTLSServer(config.TLS, &http.Server{
+			Handler:           router,
+			ReadHeaderTimeout: codex.Primary.ReadHeaderTimeout,
+			ReadTimeout:       codex.Primary.ReadTimeout,
+			WriteTimeout:      codex.Primary.WriteTimeout,
+			IdleTimeout:       codex.Primary.IdleTimeout,
+			MaxHeaderBytes:    codex.Primary.MaxHeaderBytes,
+		}, logger)
+		exitIfError(logger, emperror.Wrap(err, "failed to create TLS server"))
+		s.tlsServer.Start()
+		logging.Info(logger).Log(logging.MessageKey(), "Serving webhook endpoint over TLS", "address", config.TLS.Address,
+			"mTLS", config.TLS.ClientCACertFile != "")
+		// events are only accepted over TLS.
+		primaryHandler = mux.NewRouter()
+	}
 	var runnable concurrent.Runnable
-	_, runnable, s.done = codex.Prepare(logger, nil, metricsRegistry, router)
+	_, runnable, s.done = codex.Prepare(logger, nil, metricsRegistry, primaryHandler)
 	s.waitGroup, s.shutdown, err = concurrent.Execute(runnable)
 	exitIfError(logger, emperror.Wrap(err, "unable to start device manager"))
 
 	logging.Info(logger).Log(logging.MessageKey(), fmt.Sprintf("%s is up and running!", applicationName), "elapsedTime", time.Since(start))
 
-	waitUntilShutdown(logger, s, database)
+	exitIfError(logger, waitUntilShutdown(logger, s, database))
 	logging.Info(logger).Log(logging.MessageKey(), "Svalinn has shut down")
 }
 
+// checkRules warns about the rules of each registration that can never
+// receive events given the events registered.
+func checkRules(logger log.Logger, registrations []registration) {
+	for _, r := range registrations {
+		if !r.shouldRegister() {
+			continue
+		}
+		unreachable, err := r.unreachableRules()
+		if err != nil {
+			logging.Warn(logger, emperror.Context(err)...).Log(logging.MessageKey(), "Failed to check rules against the registered events",
+				"registration", r.name, logging.ErrorKey(), err.Error())
+			continue
+		}
+		for _, rule := range unreachable {
+			logging.Warn(logger).Log(logging.MessageKey(), "Rule can never receive events given the registered events",
+				"registration", r.name, "regex", rule.Regex, "events", r.request.Events)
+		}
+	}
+}
+
 func printVersion(f *pflag.FlagSet, arguments []string) (error, bool) {
 	printVer := f.BoolP("version", "v", false, "displays the version number")
 	if err := f.Parse(arguments); err != nil {
@@ -294,21 +486,44 @@ func setupDb(config *SvalinnConfig, logger log.Logger, metricsRegistry xmetrics.
 	d.health = health.New()
 	d.health.Logger = healthlogger.NewHealthLogger(logger)
 
-	dbConn, err := cassandra.CreateDbConnection(config.Db, metricsRegistry, d.health)
+	dbType, err := config.Db.dbType()
 	if err != nil {
 		return database{}, err
 	}
+	var updater blacklist.Updater
+	if dbType == dbTypeMemory {
+		d.memory = newMemoryDb(config.Db.Memory)
+		d.dbClose = func() error { return nil }
+		d.inserter = d.memory
+		updater = d.memory
+	} else {
+		dbConn, err := cassandra.CreateDbConnection(config.Db.Config, metricsRegistry, d.health)
+		if err != nil {
+			return database{}, err
+		}
 
-	d.dbClose = dbConn.Close
+		d.dbClose = dbConn.Close
 
-	if config.InsertRetries.MaxElapsedTime >= 0 {
-		d.inserter = dbretry.CreateRetryInsertService(
-			dbConn,
-			dbretry.WithBackoff(config.InsertRetries),
-			dbretry.WithMeasures(metricsRegistry),
-		)
-	} else {
-		d.inserter = dbConn
+		if config.InsertRetries.MaxElapsedTime >= 0 {
+			d.inserter = dbretry.CreateRetryInsertService(
+				dbConn,
+				dbretry.WithBackoff(config.InsertRetries),
+				dbretry.WithMeasures(metricsRegistry),
+			)
+		} else {
+			d.inserter = dbConn
+		}
+		updater = dbConn
+	}
+
+	d.inserter, d.sinksClose, err = newFanout(sink.PrimaryName, d.inserter, config.Sinks, sinkOptions{
+		metricsRegistry: metricsRegistry,
+		health:          d.health,
+		logger:          logger,
+	})
+	if err != nil {
+		d.dbClose()
+		return database{}, err
 	}
 
 	d.blacklistStop = make(chan struct{}, 1)
@@ -316,7 +531,7 @@ func setupDb(config *SvalinnConfig, logger log.Logger, metricsRegistry xmetrics.
 		Logger:         logger,
 		UpdateInterval: config.BlacklistInterval,
 	}
-	d.blacklistRefresher = blacklist.NewListRefresher(blacklistConfig, dbConn, d.blacklistStop)
+	d.blacklistRefresher = blacklist.NewListRefresher(blacklistConfig, updater, d.blacklistStop)
 	return d, nil
 
 }
@@ -335,9 +550,13 @@ func startHealth(logger log.Logger, health *health.Health, config *SvalinnConfig
 	}
 }
 
-func waitUntilShutdown(logger log.Logger, s *Svalinn, database database) {
+// waitUntilShutdown waits for a signal or failure, then shuts Svalinn down.
+// The error returned is the failure that caused the shutdown, if any.
+func waitUntilShutdown(logger log.Logger, s *Svalinn, database database) error {
 	signals := make(chan os.Signal, 10)
 	signal.Notify(signals, os.Kill, os.Interrupt) //nolint:staticcheck // this will be fixed with uber fx
+	registrationFailed := s.registerers.Failed()
+	var shutdownErr error
 	for exit := false; !exit; {
 		select {
 		case s := <-signals:
@@ -346,6 +565,9 @@ func waitUntilShutdown(logger log.Logger, s *Svalinn, database database) {
 		case <-s.done:
 			logging.Error(logger).Log(logging.MessageKey(), "one or more servers exited")
 			exit = true
+		case shutdownErr = <-registrationFailed:
+			logging.Error(logger).Log(logging.MessageKey(), "exiting due to webhook registration failure", logging.ErrorKey(), shutdownErr.Error())
+			exit = true
 		}
 	}
 
@@ -354,17 +576,57 @@ func waitUntilShutdown(logger log.Logger, s *Svalinn, database database) {
 		logging.Error(logger, emperror.Context(err)...).Log(logging.MessageKey(), "stopping health endpoint failed",
 			logging.ErrorKey(), err.Error())
 	}
-	s.registerer.Stop()
+	s.registerers.Stop()
+	s.registerers.Unregister()
+	if s.adminServer != nil {
+		err = s.adminServer.Shutdown(context.Background())
+		if err != nil {
+			logging.Error(logger, emperror.Context(err)...).Log(logging.MessageKey(), "stopping admin server failed",
+				logging.ErrorKey(), err.Error())
+		}
+	}
+	s.app.startShutdown()
+	if s.tlsServer != nil {
+		err = s.tlsServer.Stop(context.Background())
+		if err != nil {
+			logging.Error(logger, emperror.Context(err)...).Log(logging.MessageKey(), "stopping TLS server failed",
+				logging.ErrorKey(), err.Error())
+		}
+	}
+	if s.grpcServer != nil {
+		s.grpcServer.Stop()
+	}
+	if s.kafkaSource != nil {
+		err = s.kafkaSource.Stop()
+		if err != nil {
+			logging.Error(logger, emperror.Context(err)...).Log(logging.MessageKey(), "stopping kafka source failed",
+				logging.ErrorKey(), err.Error())
+		}
+	}
 	close(database.blacklistStop)
 	close(s.shutdown)
 	s.waitGroup.Wait()
 	s.requestParser.Stop()
 	s.batchInserter.Stop()
+	if s.priorityBatch != nil {
+		s.priorityBatch.Stop()
+	}
+	err = s.targets.Stop()
+	if err != nil {
+		logging.Error(logger, emperror.Context(err)...).Log(logging.MessageKey(), "stopping storage targets failed",
+			logging.ErrorKey(), err.Error())
+	}
+	err = database.sinksClose()
+	if err != nil {
+		logging.Error(logger, emperror.Context(err)...).Log(logging.MessageKey(), "closing sinks failed",
+			logging.ErrorKey(), err.Error())
+	}
 	err = database.dbClose()
 	if err != nil {
 		logging.Error(logger, emperror.Context(err)...).Log(logging.MessageKey(), "closing database threads failed",
 			logging.ErrorKey(), err.Error())
 	}
+	return shutdownErr
 }
 
 func main() {
@@ -0,0 +1,141 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/goph/emperror"
+	"github.com/xmidt-org/codex-db"
+	"github.com/xmidt-org/codex-db/blacklist"
+	"github.com/xmidt-org/codex-db/cassandra"
+)
+
+const (
+	dbTypeCassandra = "cassandra"
+	dbTypeMemory    = "memory"
+
+	defaultMemoryMaxRecords = 10000
+)
+
+var (
+	errUnknownDbType = errors.New("unknown db type")
+)
+
+// DbConfig chooses the database records are inserted into and configures it.
+type DbConfig struct {
+	// Type is cassandra or memory.  Defaults to cassandra.
+	Type string
+
+	// Memory configures the in-memory database, which keeps the records
+	// inserted so they can be read from the admin server.  It's meant for
+	// development and integration tests, since nothing is persisted.
+	Memory MemoryDbConfig
+
+	cassandra.Config `mapstructure:",squash"`
+}
+
+// MemoryDbConfig configures the in-memory database.
+type MemoryDbConfig struct {
+	// MaxRecords is how many records are kept, dropping the oldest ones when
+	// there are more.  Defaults to 10000.
+	MaxRecords int
+
+	// Blacklist is the blacklist, which doesn't change while svalinn runs.
+	// The ids can be device ids or regular expressions.
+	Blacklist []blacklist.BlackListedItem
+}
+
+// dbType gets the configured type, checking that it's known.
+func (c DbConfig) dbType() (string, error) {
+	switch t := strings.ToLower(c.Type); t {
+	case "":
+		return dbTypeCassandra, nil
+	case dbTypeCassandra, dbTypeMemory:
+		return t, nil
+	default:
+		return "", emperror.With(errUnknownDbType, "type", c.Type)
+	}
+}
+
+// memoryDb is a database kept in memory.  It inserts records and provides
+// the blacklist, so svalinn can run without cassandra.
+type memoryDb struct {
+	lock       sync.RWMutex
+	records    []db.Record
+	maxRecords int
+	blacklist  []blacklist.BlackListedItem
+}
+
+func newMemoryDb(config MemoryDbConfig) *memoryDb {
+	m := &memoryDb{
+		maxRecords: config.MaxRecords,
+		blacklist:  config.Blacklist,
+	}
+	if m.maxRecords <= 0 {
+		m.maxRecords = defaultMemoryMaxRecords
+	}
+	return m
+}
+
+// InsertRecords keeps the records, dropping the oldest ones once there are
+// more than the max.
+func (m *memoryDb) InsertRecords(records ...db.Record) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.records = append(m.records, records...)
+	if extra := len(m.records) - m.maxRecords; extra > 0 {
+		m.records = append([]db.Record(nil), m.records[extra:]...)
+	}
+	return nil
+}
+
+// GetBlacklist gets the configured blacklist.
+func (m *memoryDb) GetBlacklist() ([]blacklist.BlackListedItem, error) {
+	return m.blacklist, nil
+}
+
+// getRecords gets the newest records for the device, or for every device if
+// the device id is empty, oldest first.  A limit of zero or less gets all of
+// them.
+func (m *memoryDb) getRecords(deviceID string, limit int) []db.Record {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	records := make([]db.Record, 0)
+	for i := len(m.records) - 1; i >= 0; i-- {
+		if limit > 0 && len(records) == limit {
+			break
+		}
+		if deviceID == "" || m.records[i].DeviceID == deviceID {
+			records = append(records, m.records[i])
+		}
+	}
+	for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+		records[i], records[j] = records[j], records[i]
+	}
+	return records
+}
+
+// clear removes every record.
+func (m *memoryDb) clear() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.records = nil
+}
@@ -0,0 +1,136 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/codex-db"
+	"github.com/xmidt-org/codex-db/batchInserter"
+	"github.com/xmidt-org/svalinn/sink"
+	"github.com/xmidt-org/webpa-common/v2/logging"
+	"github.com/xmidt-org/webpa-common/v2/xmetrics"
+)
+
+func TestDbType(t *testing.T) {
+	tests := []struct {
+		dbType       string
+		expectedType string
+		expectedErr  error
+	}{
+		{dbType: "", expectedType: dbTypeCassandra},
+		{dbType: "cassandra", expectedType: dbTypeCassandra},
+		{dbType: "Memory", expectedType: dbTypeMemory},
+		{dbType: "postgres", expectedErr: errUnknownDbType},
+	}
+	for _, tc := range tests {
+		t.Run(tc.dbType, func(t *testing.T) {
+			dbType, err := DbConfig{Type: tc.dbType}.dbType()
+			if tc.expectedErr != nil {
+				require.NotNil(t, err)
+				assert.Contains(t, err.Error(), tc.expectedErr.Error())
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expectedType, dbType)
+		})
+	}
+}
+
+func TestMemoryDb(t *testing.T) {
+	assert := assert.New(t)
+	m := newMemoryDb(MemoryDbConfig{MaxRecords: 3})
+	assert.Nil(m.InsertRecords(
+		db.Record{DeviceID: "mac:112233445566", BirthDate: 1},
+		db.Record{DeviceID: "mac:aabbccddeeff", BirthDate: 2},
+	))
+	assert.Nil(m.InsertRecords(
+		db.Record{DeviceID: "mac:112233445566", BirthDate: 3},
+		db.Record{DeviceID: "mac:112233445566", BirthDate: 4},
+	))
+
+	// the oldest record was dropped.
+	birthDates := func(records []db.Record) []int64 {
+		dates := make([]int64, 0, len(records))
+		for _, r := range records {
+			dates = append(dates, r.BirthDate)
+		}
+		return dates
+	}
+	assert.Equal([]int64{2, 3, 4}, birthDates(m.getRecords("", 0)))
+	assert.Equal([]int64{3, 4}, birthDates(m.getRecords("mac:112233445566", 0)))
+	assert.Equal([]int64{4}, birthDates(m.getRecords("mac:112233445566", 1)))
+	assert.Empty(m.getRecords("mac:000000000000", 0))
+
+	m.clear()
+	assert.Empty(m.getRecords("", 0))
+}
+
+func TestSetupMemoryDb(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	v := viper.New()
+	v.SetConfigType("yaml")
+	require.Nil(v.ReadConfig(strings.NewReader(`
+db:
+  type: memory
+  hosts:
+    - "db"
+  memory:
+    maxRecords: 10
+    blacklist:
+      - id: "mac:1122.*"
+        reason: "testing"
+`)))
+	config := new(SvalinnConfig)
+	require.Nil(v.Unmarshal(config))
+	assert.Equal([]string{"db"}, config.Db.Hosts)
+
+	d, err := setupDb(config, logging.NewTestLogger(nil, t), nil)
+	require.Nil(err)
+	defer close(d.blacklistStop)
+	require.NotNil(d.memory)
+	assert.Equal(10, d.memory.maxRecords)
+	assert.Eventually(func() bool {
+		reason, ok := d.blacklistRefresher.InList("mac:112233445566")
+		return ok && reason == "testing"
+	}, time.Second, time.Millisecond)
+	_, ok := d.blacklistRefresher.InList("mac:aabbccddeeff")
+	assert.False(ok)
+
+	// records batched for the database end up in memory.
+	registry, err := xmetrics.NewRegistry(nil, Metrics, batchInserter.Metrics, sink.Metrics)
+	require.Nil(err)
+	inserter, err := batchInserter.NewBatchInserter(batchInserter.Config{}, logging.NewTestLogger(nil, t), registry, d.inserter, NewMeasures(registry))
+	require.Nil(err)
+	inserter.Start()
+	assert.Nil(inserter.Insert(batchInserter.RecordWithTime{
+		Record:    db.Record{DeviceID: "mac:aabbccddeeff", Data: []byte("data")},
+		Beginning: time.Now(),
+	}))
+	inserter.Stop()
+	assert.Len(d.memory.getRecords("mac:aabbccddeeff", 0), 1)
+	assert.Nil(d.dbClose())
+	assert.Nil(d.sinksClose())
+}
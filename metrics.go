@@ -26,7 +26,34 @@ import (
 )
 
 const (
-	TimeInMemory = "event_time_in_memory"
+	TimeInMemory       = "event_time_in_memory"
+	RequestBodySize    = "request_body_size_bytes"
+	SignatureCounter   = "signature_validation_count"
+	ReplayCounter      = "replay_rejected_count"
+	SinceRegistered    = "time_since_registered_seconds"
+	KafkaSourceCounter = "kafka_source_messages_count"
+)
+
+const (
+	secretLabel       = "secret"
+	reasonLabel       = "reason"
+	registrationLabel = "registration"
+	outcomeLabel      = "outcome"
+
+	currentSecret  = "current"
+	previousSecret = "previous"
+
+	missingHeadersReason   = "missing_replay_headers"
+	invalidTimestampReason = "invalid_timestamp"
+	expiredTimestampReason = "timestamp_outside_window"
+	reusedNonceReason      = "reused_nonce"
+
+	storedOutcome      = "stored"
+	blacklistedOutcome = "blacklisted"
+	failedOutcome      = "failed"
+	droppedOutcome     = "dropped"
+	invalidOutcome     = "invalid"
+	rejectedOutcome    = "rejected"
 )
 
 func Metrics() []xmetrics.Metric {
@@ -36,20 +63,64 @@ func Metrics() []xmetrics.Metric {
 			Help: "The depth of the parsing queue, in seconds",
 			Type: "histogram",
 		},
+		{
+			Name:    RequestBodySize,
+			Help:    "The size of webhook request bodies, in bytes",
+			Type:    "histogram",
+			Buckets: []float64{256, 1024, 4096, 16384, 65536, 262144, 1048576, 4194304},
+		},
+		{
+			Name:       SignatureCounter,
+			Help:       "The number of requests with a valid signature, by the secret that validated it",
+			Type:       "counter",
+			LabelNames: []string{secretLabel},
+		},
+		{
+			Name:       ReplayCounter,
+			Help:       "The number of signed requests rejected as possible replays",
+			Type:       "counter",
+			LabelNames: []string{reasonLabel},
+		},
+		{
+			Name:       SinceRegistered,
+			Help:       "The time since the webhook was last registered successfully, in seconds",
+			Type:       "gauge",
+			LabelNames: []string{registrationLabel},
+		},
+		{
+			Name:       KafkaSourceCounter,
+			Help:       "The number of messages consumed from kafka, by what became of them",
+			Type:       "counter",
+			LabelNames: []string{outcomeLabel},
+		},
 	}
 }
 
 type Measures struct {
-	TimeInMemory metrics.Histogram
+	TimeInMemory    metrics.Histogram
+	RequestBodySize metrics.Histogram
+	Signatures      metrics.Counter
+	ReplayRejected  metrics.Counter
+	SinceRegistered metrics.Gauge
+	KafkaMessages   metrics.Counter
 }
 
 // NewMeasures constructs a Measures given a go-kit metrics Provider
 func NewMeasures(p provider.Provider) *Measures {
 	return &Measures{
-		TimeInMemory: p.NewHistogram(TimeInMemory, 10),
+		TimeInMemory:    p.NewHistogram(TimeInMemory, 10),
+		RequestBodySize: p.NewHistogram(RequestBodySize, 10),
+		Signatures:      p.NewCounter(SignatureCounter),
+		ReplayRejected:  p.NewCounter(ReplayCounter),
+		SinceRegistered: p.NewGauge(SinceRegistered),
+		KafkaMessages:   p.NewCounter(KafkaSourceCounter),
 	}
 }
 
 func (m *Measures) TrackTime(length time.Duration) {
 	m.TimeInMemory.Observe(length.Seconds())
 }
+
+func (m *Measures) TrackRequestSize(size int64) {
+	m.RequestBodySize.Observe(float64(size))
+}
@@ -18,9 +18,13 @@
 package main
 
 import (
+	"context"
 	"time"
 
+	"github.com/IBM/sarama"
 	"github.com/stretchr/testify/mock"
+	"github.com/xmidt-org/codex-db"
+	"github.com/xmidt-org/codex-db/batchInserter"
 	"github.com/xmidt-org/svalinn/requestParser"
 )
 
@@ -33,6 +37,20 @@ func (p *mockParser) Parse(message requestParser.WrpWithTime) error {
 	return args.Error(0)
 }
 
+func (p *mockParser) DrainTime() (time.Duration, bool) {
+	args := p.Called()
+	return args.Get(0).(time.Duration), args.Bool(1)
+}
+
+type mockHealth struct {
+	mock.Mock
+}
+
+func (h *mockHealth) Failed() bool {
+	args := h.Called()
+	return args.Bool(0)
+}
+
 type mockTimeTracker struct {
 	mock.Mock
 }
@@ -40,3 +58,71 @@ type mockTimeTracker struct {
 func (m *mockTimeTracker) TrackTime(t time.Duration) {
 	m.Called(t)
 }
+
+type mockSizeTracker struct {
+	mock.Mock
+}
+
+func (m *mockSizeTracker) TrackRequestSize(size int64) {
+	m.Called(size)
+}
+
+type mockInserter struct {
+	mock.Mock
+}
+
+func (i *mockInserter) Insert(record batchInserter.RecordWithTime) error {
+	args := i.Called(record)
+	return args.Error(0)
+}
+
+type mockDbInserter struct {
+	mock.Mock
+}
+
+func (i *mockDbInserter) InsertRecords(records ...db.Record) error {
+	args := i.Called(records)
+	return args.Error(0)
+}
+
+type mockRegisterer struct {
+	mock.Mock
+}
+
+func (r *mockRegisterer) Register() error {
+	args := r.Called()
+	return args.Error(0)
+}
+
+type mockSession struct {
+	mock.Mock
+	ctx context.Context
+}
+
+func (s *mockSession) Claims() map[string][]int32 { return nil }
+func (s *mockSession) MemberID() string           { return "" }
+func (s *mockSession) GenerationID() int32        { return 0 }
+func (s *mockSession) Commit()                    {}
+func (s *mockSession) Context() context.Context   { return s.ctx }
+
+func (s *mockSession) MarkOffset(topic string, partition int32, offset int64, metadata string) {
+	s.Called(topic, partition, offset, metadata)
+}
+
+func (s *mockSession) ResetOffset(topic string, partition int32, offset int64, metadata string) {
+	s.Called(topic, partition, offset, metadata)
+}
+
+func (s *mockSession) MarkMessage(msg *sarama.ConsumerMessage, metadata string) {
+	s.Called(msg, metadata)
+}
+
+type mockClaim struct {
+	messages chan *sarama.ConsumerMessage
+}
+
+func (c *mockClaim) Topic() string                            { return "events" }
+func (c *mockClaim) Partition() int32                         { return 0 }
+func (c *mockClaim) InitialOffset() int64                     { return 0 }
+func (c *mockClaim) HighWaterMarkOffset() int64               { return 0 }
+func (c *mockClaim) Messages() <-chan *sarama.ConsumerMessage { return c.messages }
@@ -18,59 +18,213 @@
 package main
 
 import (
+	"io"
 	"io/ioutil"
+	"math"
 	"net/http"
+	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-kit/kit/log"
+	"github.com/goph/emperror"
 	"github.com/xmidt-org/svalinn/requestParser"
+	"github.com/xmidt-org/svalinn/rules"
 	"github.com/xmidt-org/webpa-common/v2/logging"
 	"github.com/xmidt-org/wrp-go/v3"
 )
 
+const (
+	defaultMinRetryAfter = time.Second
+	defaultMaxRetryAfter = time.Minute
+)
+
 type parser interface {
 	Parse(requestParser.WrpWithTime) error
+	DrainTime() (time.Duration, bool)
 }
 
 type timeTracker interface {
 	TrackTime(time.Duration)
 }
 
+type sizeTracker interface {
+	TrackRequestSize(int64)
+}
+
+type healthChecker interface {
+	Failed() bool
+}
+
+// LoadSheddingConfig bounds the Retry-After header sent when an event is
+// rejected.
+type LoadSheddingConfig struct {
+	MinRetryAfter time.Duration
+	MaxRetryAfter time.Duration
+}
+
 type App struct {
-	parser      parser
-	logger      log.Logger
-	timeTracker timeTracker
+	parser              parser
+	logger              log.Logger
+	timeTracker         timeTracker
+	sizeTracker         sizeTracker
+	health              healthChecker
+	loadShedding        LoadSheddingConfig
+	maxRequestSize      int64
+	maxDecompressedSize int64
+	shuttingDown        int32
+}
+
+// startShutdown makes the handler turn away new events while the server is
+// shutting down.
+func (app *App) startShutdown() {
+	atomic.StoreInt32(&app.shuttingDown, 1)
+}
+
+func (app *App) isShuttingDown() bool {
+	return atomic.LoadInt32(&app.shuttingDown) == 1
 }
 
 func (app *App) handleWebhook(writer http.ResponseWriter, req *http.Request) {
+	app.handleEvent(writer, req, nil)
+}
+
+// webhookHandler handles events sent for a registration with its own rules.
+func (app *App) webhookHandler(eventRules rules.Rules) http.HandlerFunc {
+	return func(writer http.ResponseWriter, req *http.Request) {
+		app.handleEvent(writer, req, eventRules)
+	}
+}
+
+// handleEvent decodes the event in the request and queues it to be parsed
+// with the rules given, or the parser's rules if there are none.
+func (app *App) handleEvent(writer http.ResponseWriter, req *http.Request, eventRules rules.Rules) {
 	begin := time.Now()
-	var message wrp.Message
-	msgBytes, err := ioutil.ReadAll(req.Body)
-	req.Body.Close()
-	if err != nil {
-		logging.Error(app.logger).Log(logging.MessageKey(), "Could not read request body", logging.ErrorKey(), err.Error())
-		writer.WriteHeader(http.StatusBadRequest)
+	if app.isShuttingDown() {
+		app.reject(writer, requestParser.ErrShuttingDown)
 		app.timeTracker.TrackTime(time.Since(begin))
 		return
 	}
 
-	err = wrp.NewDecoderBytes(msgBytes, wrp.Msgpack).Decode(&message)
+	maxSize := app.maxRequestSize
+	if maxSize <= 0 {
+		maxSize = defaultMaxRequestSize
+	}
+	maxDecompressedSize := app.maxDecompressedSize
+	if maxDecompressedSize <= 0 {
+		maxDecompressedSize = defaultMaxDecompressedSize
+	}
+	raw := newLimitedBody(req.Body, maxSize)
+	defer req.Body.Close()
+	decompressed, err := decompressBody(raw, req.Header.Get("Content-Encoding"), maxDecompressedSize)
 	if err != nil {
-		logging.Error(app.logger).Log(logging.MessageKey(), "Could not decode request body", logging.ErrorKey(), err.Error())
+		status := http.StatusBadRequest
+		switch {
+		case raw.exceeded:
+			status = http.StatusRequestEntityTooLarge
+		case err == errUnsupportedEncoding:
+			status = http.StatusUnsupportedMediaType
+		}
+		logging.Error(app.logger).Log(logging.MessageKey(), "Could not decompress request body", logging.ErrorKey(), err.Error(),
+			"contentEncoding", req.Header.Get("Content-Encoding"))
+		writer.WriteHeader(status)
+		app.timeTracker.TrackTime(time.Since(begin))
+		return
+	}
+	defer decompressed.Close()
+
+	// the decompressed body has its own limit so a small compressed body
+	// can't expand into something huge.
+	body := newLimitedBody(decompressed, maxDecompressedSize)
+	var message wrp.Message
+	decodeErr := wrp.NewDecoder(body, wrp.Msgpack).Decode(&message)
+	// read the rest of the body so it counts towards the size of the request.
+	_, readErr := io.Copy(ioutil.Discard, body)
+	if app.sizeTracker != nil {
+		app.sizeTracker.TrackRequestSize(raw.read)
+	}
+
+	if raw.exceeded || body.exceeded {
+		logging.Error(app.logger).Log(logging.MessageKey(), "Request body is too large", "maxRequestSize", maxSize,
+			"maxDecompressedSize", maxDecompressedSize)
+		writer.WriteHeader(http.StatusRequestEntityTooLarge)
+		app.timeTracker.TrackTime(time.Since(begin))
+		return
+	}
+	if decodeErr != nil {
+		logging.Error(app.logger).Log(logging.MessageKey(), "Could not decode request body", logging.ErrorKey(), decodeErr.Error())
 		writer.WriteHeader(http.StatusBadRequest)
 		app.timeTracker.TrackTime(time.Since(begin))
 		return
 	}
+	if readErr != nil {
+		logging.Error(app.logger).Log(logging.MessageKey(), "Could not read request body", logging.ErrorKey(), readErr.Error())
+		writer.WriteHeader(http.StatusBadRequest)
+		app.timeTracker.TrackTime(time.Since(begin))
+		return
+	}
+
+	if err := authorizePartners(req.Context(), &message); err != nil {
+		logging.Error(app.logger, emperror.Context(err)...).Log(logging.MessageKey(), "Event isn't allowed by the token", logging.ErrorKey(), err.Error())
+		writer.WriteHeader(http.StatusForbidden)
+		app.timeTracker.TrackTime(time.Since(begin))
+		return
+	}
 
 	logging.Debug(app.logger).Log(logging.MessageKey(), "message info", "messageType", message.Type, "fullMsg", message)
-	err = app.parser.Parse(requestParser.WrpWithTime{Message: message, Beginning: begin})
+	err = app.parser.Parse(requestParser.WrpWithTime{Message: message, Beginning: begin, Rules: eventRules})
 	if err != nil {
 		logging.Warn(app.logger).Log(logging.ErrorKey(), err.Error())
-		// end := time.Now()
-		// todo: send beginning and end times to metric handler
-		writer.WriteHeader(http.StatusTooManyRequests)
+		app.reject(writer, err)
 		app.timeTracker.TrackTime(time.Since(begin))
 		return
 	}
 	writer.WriteHeader(http.StatusAccepted)
 }
+
+// reject tells the sender that the event wasn't accepted and when to try
+// again.
+func (app *App) reject(writer http.ResponseWriter, err error) {
+	status, retryAfter := app.rejection(err)
+	writer.Header().Set("Retry-After", retryAfterSeconds(retryAfter))
+	writer.WriteHeader(status)
+}
+
+// rejection decides the status and retry time for an event that wasn't
+// accepted.  Shutting down and an unhealthy database are answered with a 503,
+// since they won't be fixed by the queue draining.  Otherwise the queue is
+// full, which is answered with a 429 and the time it should take the queue
+// to drain.
+func (app *App) rejection(err error) (int, time.Duration) {
+	minRetry, maxRetry := app.loadShedding.MinRetryAfter, app.loadShedding.MaxRetryAfter
+	if minRetry <= 0 {
+		minRetry = defaultMinRetryAfter
+	}
+	if maxRetry < minRetry {
+		maxRetry = defaultMaxRetryAfter
+	}
+
+	status, retryAfter := http.StatusTooManyRequests, maxRetry
+	switch {
+	case err == requestParser.ErrShuttingDown:
+		status, retryAfter = http.StatusServiceUnavailable, minRetry
+	case app.health != nil && app.health.Failed():
+		status = http.StatusServiceUnavailable
+	default:
+		if drainTime, ok := app.parser.DrainTime(); ok {
+			retryAfter = drainTime
+		}
+	}
+	if retryAfter < minRetry {
+		retryAfter = minRetry
+	}
+	if retryAfter > maxRetry {
+		retryAfter = maxRetry
+	}
+	return status, retryAfter
+}
+
+// retryAfterSeconds formats a retry time as whole seconds, rounding up.
+func retryAfterSeconds(retryAfter time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(retryAfter.Seconds())))
+}
@@ -19,14 +19,20 @@ package main
 
 import (
 	"bytes"
+	"compress/gzip"
 	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/klauspost/compress/zstd"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
+	"github.com/xmidt-org/svalinn/requestParser"
+	"github.com/xmidt-org/svalinn/rules"
 	"github.com/xmidt-org/webpa-common/v2/logging"
 	"github.com/xmidt-org/wrp-go/v3"
 )
@@ -39,11 +45,21 @@ func TestHandleWebhook(t *testing.T) {
 	}
 
 	tests := []struct {
-		description    string
-		requestBody    interface{}
-		expectedHeader int
-		parseCalled    bool
-		parseErr       error
+		description         string
+		requestBody         interface{}
+		expectedHeader      int
+		parseCalled         bool
+		parseErr            error
+		drainCalled         bool
+		drainTime           time.Duration
+		drainKnown          bool
+		healthFailed        bool
+		shuttingDown        bool
+		maxRequestSize      int64
+		maxDecompressedSize int64
+		contentEncoding     string
+		corruptBody         bool
+		expectedRetryAfter  string
 	}{
 		{
 			description:    "Success",
@@ -57,11 +73,133 @@ func TestHandleWebhook(t *testing.T) {
 			expectedHeader: http.StatusBadRequest,
 		},
 		{
-			description:    "Parse Error",
+			description:    "Body Too Large",
 			requestBody:    goodMsg,
-			expectedHeader: http.StatusTooManyRequests,
+			expectedHeader: http.StatusRequestEntityTooLarge,
+			maxRequestSize: 10,
+		},
+		{
+			description:    "Body At Max Size",
+			requestBody:    goodMsg,
+			expectedHeader: http.StatusAccepted,
 			parseCalled:    true,
-			parseErr:       errors.New("test parse error"),
+			maxRequestSize: 38,
+		},
+		{
+			description:    "Body One Byte Too Large",
+			requestBody:    goodMsg,
+			expectedHeader: http.StatusRequestEntityTooLarge,
+			maxRequestSize: 37,
+		},
+		{
+			description:     "Gzip Success",
+			requestBody:     goodMsg,
+			expectedHeader:  http.StatusAccepted,
+			parseCalled:     true,
+			contentEncoding: "gzip",
+		},
+		{
+			description:     "Zstd Success",
+			requestBody:     goodMsg,
+			expectedHeader:  http.StatusAccepted,
+			parseCalled:     true,
+			contentEncoding: "zstd",
+		},
+		{
+			description:     "Identity Success",
+			requestBody:     goodMsg,
+			expectedHeader:  http.StatusAccepted,
+			parseCalled:     true,
+			contentEncoding: "identity",
+		},
+		{
+			description:     "Unsupported Encoding",
+			requestBody:     goodMsg,
+			expectedHeader:  http.StatusUnsupportedMediaType,
+			contentEncoding: "br",
+		},
+		{
+			description:     "Corrupt Gzip",
+			requestBody:     goodMsg,
+			expectedHeader:  http.StatusBadRequest,
+			contentEncoding: "gzip",
+			corruptBody:     true,
+		},
+		{
+			description:     "Corrupt Zstd",
+			requestBody:     goodMsg,
+			expectedHeader:  http.StatusBadRequest,
+			contentEncoding: "zstd",
+			corruptBody:     true,
+		},
+		{
+			description:         "Gzip Decompressed Too Large",
+			requestBody:         goodMsg,
+			expectedHeader:      http.StatusRequestEntityTooLarge,
+			contentEncoding:     "gzip",
+			maxDecompressedSize: 37,
+		},
+		{
+			description:         "Zstd Decompressed Too Large",
+			requestBody:         goodMsg,
+			expectedHeader:      http.StatusRequestEntityTooLarge,
+			contentEncoding:     "zstd",
+			maxDecompressedSize: 37,
+		},
+		{
+			description:        "Parse Error",
+			requestBody:        goodMsg,
+			expectedHeader:     http.StatusTooManyRequests,
+			parseCalled:        true,
+			parseErr:           errors.New("test parse error"),
+			drainCalled:        true,
+			drainTime:          2500 * time.Millisecond,
+			drainKnown:         true,
+			expectedRetryAfter: "3",
+		},
+		{
+			description:        "Queue Full Unknown Drain Time",
+			requestBody:        goodMsg,
+			expectedHeader:     http.StatusTooManyRequests,
+			parseCalled:        true,
+			parseErr:           requestParser.ErrQueueFull,
+			drainCalled:        true,
+			expectedRetryAfter: "60",
+		},
+		{
+			description:        "Queue Full Small Drain Time",
+			requestBody:        goodMsg,
+			expectedHeader:     http.StatusTooManyRequests,
+			parseCalled:        true,
+			parseErr:           requestParser.ErrQueueFull,
+			drainCalled:        true,
+			drainTime:          time.Millisecond,
+			drainKnown:         true,
+			expectedRetryAfter: "1",
+		},
+		{
+			description:        "Queue Full Unhealthy Database",
+			requestBody:        goodMsg,
+			expectedHeader:     http.StatusServiceUnavailable,
+			parseCalled:        true,
+			parseErr:           requestParser.ErrQueueFull,
+			healthFailed:       true,
+			expectedRetryAfter: "60",
+		},
+		{
+			description:        "Parser Shutting Down",
+			requestBody:        goodMsg,
+			expectedHeader:     http.StatusServiceUnavailable,
+			parseCalled:        true,
+			parseErr:           requestParser.ErrShuttingDown,
+			expectedRetryAfter: "1",
+		},
+		{
+			description:        "Server Shutting Down",
+			requestBody:        goodMsg,
+			expectedHeader:     http.StatusServiceUnavailable,
+			shuttingDown:       true,
+			expectedRetryAfter: "1",
 		},
 	}
 
@@ -72,16 +210,33 @@ func TestHandleWebhook(t *testing.T) {
 			if tc.parseCalled {
 				mockParser.On("Parse", mock.Anything).Return(tc.parseErr).Once()
 			}
+			if tc.drainCalled {
+				mockParser.On("DrainTime").Return(tc.drainTime, tc.drainKnown).Once()
+			}
+			mockHealth := new(mockHealth)
+			mockHealth.On("Failed").Return(tc.healthFailed)
 
 			mockTimeTracker := new(mockTimeTracker)
 			if tc.expectedHeader != http.StatusAccepted {
 				mockTimeTracker.On("TrackTime", mock.Anything).Once()
 			}
 
+			mockSizeTracker := new(mockSizeTracker)
+			if !tc.shuttingDown && tc.expectedHeader != http.StatusUnsupportedMediaType {
+				mockSizeTracker.On("TrackRequestSize", mock.Anything).Once()
+			}
+
 			app := &App{
-				parser:      mockParser,
-				logger:      logging.DefaultLogger(),
-				timeTracker: mockTimeTracker,
+				parser:              mockParser,
+				logger:              logging.DefaultLogger(),
+				timeTracker:         mockTimeTracker,
+				sizeTracker:         mockSizeTracker,
+				health:              mockHealth,
+				maxRequestSize:      tc.maxRequestSize,
+				maxDecompressedSize: tc.maxDecompressedSize,
+			}
+			if tc.shuttingDown {
+				app.startShutdown()
 			}
 			rr := httptest.NewRecorder()
 			var marshaledMsg []byte
@@ -91,13 +246,73 @@ func TestHandleWebhook(t *testing.T) {
 				assert.Nil(err)
 			}
 			assert.NotNil(marshaledMsg)
+			marshaledMsg = encode(t, tc.contentEncoding, marshaledMsg)
+			if tc.corruptBody {
+				marshaledMsg = marshaledMsg[:len(marshaledMsg)/2]
+			}
 			request, err := http.NewRequest(http.MethodGet, "/", bytes.NewReader(marshaledMsg))
 			assert.Nil(err)
+			if tc.contentEncoding != "" {
+				request.Header.Set("Content-Encoding", tc.contentEncoding)
+			}
 
 			app.handleWebhook(rr, request)
 			mockParser.AssertExpectations(t)
 			mockTimeTracker.AssertExpectations(t)
+			mockSizeTracker.AssertExpectations(t)
 			assert.Equal(tc.expectedHeader, rr.Code)
+			assert.Equal(tc.expectedRetryAfter, rr.Header().Get("Retry-After"))
 		})
 	}
 }
+
+func TestWebhookHandlerRules(t *testing.T) {
+	assert := assert.New(t)
+	eventRules, err := rules.NewRules([]rules.RuleConfig{{Regex: ".*", EventType: "state"}})
+	assert.Nil(err)
+
+	mockParser := new(mockParser)
+	mockParser.On("Parse", mock.MatchedBy(func(w requestParser.WrpWithTime) bool {
+		return len(w.Rules) == 1 && w.Message.Destination == "test"
+	})).Return(nil).Once()
+	mockSizeTracker := new(mockSizeTracker)
+	mockSizeTracker.On("TrackRequestSize", mock.Anything).Once()
+	app := &App{
+		parser:      mockParser,
+		logger:      logging.DefaultLogger(),
+		timeTracker: new(mockTimeTracker),
+		sizeTracker: mockSizeTracker,
+	}
+
+	var body []byte
+	err = wrp.NewEncoderBytes(&body, wrp.Msgpack).Encode(wrp.Message{
+		Type:        wrp.SimpleEventMessageType,
+		Source:      "test",
+		Destination: "test",
+	})
+	assert.Nil(err)
+	rr := httptest.NewRecorder()
+	app.webhookHandler(eventRules).ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body)))
+	assert.Equal(http.StatusAccepted, rr.Code)
+	mockParser.AssertExpectations(t)
+}
+
+func encode(t *testing.T, encoding string, b []byte) []byte {
+	var buf bytes.Buffer
+	switch encoding {
+	case "gzip":
+		w := gzip.NewWriter(&buf)
+		_, err := w.Write(b)
+		require.Nil(t, err)
+		require.Nil(t, w.Close())
+	case "zstd":
+		w, err := zstd.NewWriter(&buf)
+		require.Nil(t, err)
+		_, err = w.Write(b)
+		require.Nil(t, err)
+		require.Nil(t, w.Close())
+	default:
+		return b
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,50 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"github.com/xmidt-org/codex-db/batchInserter"
+	"github.com/xmidt-org/svalinn/rules"
+)
+
+type PriorityBatchInserterConfig struct {
+	Enabled       bool
+	BatchInserter batchInserter.Config
+}
+
+type recordInserter interface {
+	Insert(batchInserter.RecordWithTime) error
+}
+
+// priorityInserter sends high priority records to their own batch inserter,
+// so they aren't stuck in the queue behind the rest of the records.
+type priorityInserter struct {
+	inserter     recordInserter
+	highPriority recordInserter
+}
+
+func (p *priorityInserter) Insert(record batchInserter.RecordWithTime) error {
+	return p.inserter.Insert(record)
+}
+
+func (p *priorityInserter) InsertWithPriority(record batchInserter.RecordWithTime, priority rules.Priority) error {
+	if priority == rules.HighPriority {
+		return p.highPriority.Insert(record)
+	}
+	return p.inserter.Insert(record)
+}
@@ -0,0 +1,90 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/xmidt-org/codex-db/batchInserter"
+	"github.com/xmidt-org/svalinn/rules"
+)
+
+func TestPriorityInserter(t *testing.T) {
+	tests := []struct {
+		description string
+		priority    rules.Priority
+		usePriority bool
+		expectHigh  bool
+		insertErr   error
+		expectedErr error
+	}{
+		{
+			description: "No Priority",
+		},
+		{
+			description: "Low Priority",
+			priority:    rules.LowPriority,
+			usePriority: true,
+		},
+		{
+			description: "Normal Priority",
+			priority:    rules.NormalPriority,
+			usePriority: true,
+		},
+		{
+			description: "High Priority",
+			priority:    rules.HighPriority,
+			usePriority: true,
+			expectHigh:  true,
+		},
+		{
+			description: "High Priority Error",
+			priority:    rules.HighPriority,
+			usePriority: true,
+			expectHigh:  true,
+			insertErr:   errors.New("test insert error"),
+			expectedErr: errors.New("test insert error"),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			normal, high := new(mockInserter), new(mockInserter)
+			if tc.expectHigh {
+				high.On("Insert", mock.Anything).Return(tc.insertErr).Once()
+			} else {
+				normal.On("Insert", mock.Anything).Return(tc.insertErr).Once()
+			}
+			p := &priorityInserter{inserter: normal, highPriority: high}
+
+			var err error
+			if tc.usePriority {
+				err = p.InsertWithPriority(batchInserter.RecordWithTime{}, tc.priority)
+			} else {
+				err = p.Insert(batchInserter.RecordWithTime{})
+			}
+			assert.Equal(tc.expectedErr, err)
+			normal.AssertExpectations(t)
+			high.AssertExpectations(t)
+		})
+	}
+}
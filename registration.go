@@ -0,0 +1,434 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/InVisionApp/go-health/v2"
+	"github.com/cenkalti/backoff/v3"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
+	"github.com/goph/emperror"
+	"github.com/xmidt-org/webpa-common/v2/logging"
+	webhook "github.com/xmidt-org/wrp-listener"
+	"github.com/xmidt-org/wrp-listener/webhookClient"
+)
+
+const (
+	keepRetryingPolicy = "keepretrying"
+	failFastPolicy     = "failfast"
+
+	defaultRetryInitialInterval = time.Second
+	defaultRetryMaxInterval     = time.Minute
+	defaultFailFastElapsedTime  = 5 * time.Minute
+
+	registrationCheckName     = "webhook-registration"
+	registrationCheckInterval = 10 * time.Second
+	sinceSuccessInterval      = 5 * time.Second
+)
+
+var (
+	errUnknownFailurePolicy = errors.New("unknown registration failure policy")
+	errNotRegistered        = errors.New("webhook hasn't been registered yet")
+	errRegistrationFailing  = errors.New("webhook registration is failing")
+	errRegistrationGaveUp   = errors.New("gave up registering the webhook")
+)
+
+// RegistrationRetryConfig sets up retrying failed webhook registrations.
+type RegistrationRetryConfig struct {
+	// FailurePolicy is keepRetrying or failFast.  With failFast, Svalinn
+	// exits if the first registration doesn't succeed before the backoff's
+	// max elapsed time.  Once registered, failures are always retried.
+	FailurePolicy string
+
+	// Backoff is how long to wait between attempts after a failure.  The wait
+	// is never longer than the registration interval.
+	Backoff backoff.ExponentialBackOff
+}
+
+// registrationStatus is the outcome of the latest registration attempts.
+type registrationStatus struct {
+	LastAttempt         time.Time `json:"lastAttempt"`
+	LastSuccess         time.Time `json:"lastSuccess"`
+	LastError           string    `json:"lastError,omitempty"`
+	LastStatusCode      int       `json:"lastStatusCode,omitempty"`
+	ConsecutiveFailures int       `json:"consecutiveFailures"`
+}
+
+// supervisorConfig is what a registrationSupervisor needs to register a
+// webhook.
+type supervisorConfig struct {
+	name string
+
+	// request is what's registered, reported by the admin endpoint.
+	request      webhook.W
+	registerer   webhookClient.Registerer
+	unregisterer unregisterer
+
+	// responses records the status code of the registerer's requests.
+	responses *responseRecorder
+
+	interval     time.Duration
+	retry        RegistrationRetryConfig
+	measures     *webhookClient.Measures
+	sinceSuccess metrics.Gauge
+	logger       log.Logger
+}
+
+// registrationSupervisor registers the webhook at an interval, retrying with
+// a backoff when registration fails.  It keeps the status of registration
+// so it can be reported as a health check.
+type registrationSupervisor struct {
+	name         string
+	request      webhook.W
+	registerer   webhookClient.Registerer
+	unregisterer unregisterer
+	responses    *responseRecorder
+	interval     time.Duration
+	backoff      *backoff.ExponentialBackOff
+	failFast     bool
+	measures     *webhookClient.Measures
+	sinceSuccess metrics.Gauge
+	logger       log.Logger
+	now          func() time.Time
+	started      time.Time
+
+	lock   sync.RWMutex
+	status registrationStatus
+
+	trigger  chan struct{}
+	shutdown chan struct{}
+	stopped  chan struct{}
+	failed   chan error
+}
+
+func newRegistrationSupervisor(config supervisorConfig) (*registrationSupervisor, error) {
+	if config.interval <= 0 {
+		return nil, errors.New("registration interval must be positive")
+	}
+	failFast := false
+	switch strings.ToLower(config.retry.FailurePolicy) {
+	case "", keepRetryingPolicy:
+	case failFastPolicy:
+		failFast = true
+	default:
+		return nil, emperror.With(errUnknownFailurePolicy, "policy", config.retry.FailurePolicy)
+	}
+
+	b := config.retry.Backoff
+	if b.InitialInterval <= 0 {
+		b.InitialInterval = defaultRetryInitialInterval
+	}
+	if b.MaxInterval <= 0 {
+		b.MaxInterval = defaultRetryMaxInterval
+	}
+	if b.MaxInterval > config.interval {
+		b.MaxInterval = config.interval
+	}
+	if b.Multiplier <= 0 {
+		b.Multiplier = backoff.DefaultMultiplier
+	}
+	switch {
+	case !failFast:
+		// retry until registration succeeds.
+		b.MaxElapsedTime = 0
+	case b.MaxElapsedTime <= 0:
+		b.MaxElapsedTime = defaultFailFastElapsedTime
+	}
+	b.Clock = backoff.SystemClock
+	b.Reset()
+
+	logger := config.logger
+	if logger == nil {
+		logger = logging.DefaultLogger()
+	}
+	sinceSuccess := config.sinceSuccess
+	if sinceSuccess == nil {
+		sinceSuccess = discard.NewGauge()
+	}
+	return &registrationSupervisor{
+		name:         config.name,
+		request:      config.request,
+		registerer:   config.registerer,
+		unregisterer: config.unregisterer,
+		responses:    config.responses,
+		interval:     config.interval,
+		backoff:      &b,
+		failFast:     failFast,
+		measures:     config.measures,
+		sinceSuccess: sinceSuccess,
+		logger:       logger,
+		now:          time.Now,
+		started:      time.Now(),
+		trigger:      make(chan struct{}, 1),
+		shutdown:     make(chan struct{}),
+		stopped:      make(chan struct{}),
+		failed:       make(chan error, 1),
+	}, nil
+}
+
+// Start begins registering the webhook.
+func (r *registrationSupervisor) Start() {
+	go r.run()
+}
+
+// Stop stops registering the webhook and waits for the current attempt to
+// finish.
+func (r *registrationSupervisor) Stop() {
+	close(r.shutdown)
+	<-r.stopped
+}
+
+// Unregister removes the registration if the webhook was registered.  The
+// supervisor should be stopped first, so it doesn't register again.
+func (r *registrationSupervisor) Unregister() {
+	if r.unregisterer == nil || r.getStatus().LastSuccess.IsZero() {
+		return
+	}
+	if err := r.unregisterer.Unregister(); err != nil {
+		logging.Error(r.logger, emperror.Context(err)...).Log(logging.MessageKey(), "Failed to unregister webhook",
+			logging.ErrorKey(), err.Error())
+		return
+	}
+	logging.Info(r.logger).Log(logging.MessageKey(), "Unregistered webhook")
+}
+
+// Failed receives an error if the supervisor gives up on registering.
+func (r *registrationSupervisor) Failed() <-chan error {
+	return r.failed
+}
+
+// RegisterNow registers the webhook without waiting for the next attempt.
+func (r *registrationSupervisor) RegisterNow() {
+	select {
+	case r.trigger <- struct{}{}:
+	default:
+		// an attempt is already waiting to be made.
+	}
+}
+
+func (r *registrationSupervisor) run() {
+	defer close(r.stopped)
+	ticker := time.NewTicker(sinceSuccessInterval)
+	defer ticker.Stop()
+	for {
+		wait := r.interval
+		if !r.register() {
+			wait = r.backoff.NextBackOff()
+			if wait == backoff.Stop {
+				err := emperror.With(errRegistrationGaveUp, "attempts", r.getStatus().ConsecutiveFailures)
+				logging.Error(r.logger).Log(logging.MessageKey(), "Giving up on registering the webhook", logging.ErrorKey(), err.Error())
+				r.failed <- err
+				return
+			}
+		}
+		r.updateSinceSuccess()
+		if !r.wait(wait, ticker.C) {
+			return
+		}
+	}
+}
+
+// wait waits until the next attempt should be made, which is after the
+// duration or when asked to register now.  It returns false if the supervisor
+// is stopped first.
+func (r *registrationSupervisor) wait(d time.Duration, ticks <-chan time.Time) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	for {
+		select {
+		case <-r.shutdown:
+			return false
+		case <-r.trigger:
+			return true
+		case <-timer.C:
+			return true
+		case <-ticks:
+			r.updateSinceSuccess()
+		}
+	}
+}
+
+// updateSinceSuccess sets the time since the last successful registration,
+// or since the supervisor was created if it hasn't registered yet.
+func (r *registrationSupervisor) updateSinceSuccess() {
+	last := r.getStatus().LastSuccess
+	if last.IsZero() {
+		last = r.started
+	}
+	r.sinceSuccess.Set(r.now().Sub(last).Seconds())
+}
+
+// register attempts to register once, recording the outcome.
+func (r *registrationSupervisor) register() bool {
+	err := r.registerer.Register()
+	now := r.now()
+
+	r.lock.Lock()
+	r.status.LastAttempt = now
+	r.status.LastStatusCode = r.responses.lastStatusCode()
+	if err != nil {
+		r.status.LastError = err.Error()
+		r.status.ConsecutiveFailures++
+	} else {
+		r.status.LastSuccess = now
+		r.status.LastError = ""
+		r.status.ConsecutiveFailures = 0
+	}
+	failures := r.status.ConsecutiveFailures
+	r.lock.Unlock()
+
+	if err != nil {
+		r.measures.WebhookRegistrationOutcome.With(webhookClient.OutcomeLabel, webhookClient.FailureOutcome,
+			webhookClient.ReasonLabel, webhookClient.GetReasonCode(err).LabelValue()).Add(1.0)
+		logging.Error(r.logger, emperror.Context(err)...).Log(logging.MessageKey(), "Failed to register webhook",
+			logging.ErrorKey(), err.Error(), "consecutiveFailures", failures)
+		return false
+	}
+	r.measures.WebhookRegistrationOutcome.With(webhookClient.OutcomeLabel, webhookClient.SuccessOutcome,
+		webhookClient.ReasonLabel, "").Add(1.0)
+	logging.Info(r.logger).Log(logging.MessageKey(), "Successfully registered webhook")
+	if r.failFast {
+		// after the first registration, failures are retried until they
+		// succeed.
+		r.failFast = false
+		r.backoff.MaxElapsedTime = 0
+	}
+	r.backoff.Reset()
+	return true
+}
+
+// Status reports the registration status as a health check, failing until
+// the webhook is registered and while the latest attempt failed.
+func (r *registrationSupervisor) Status() (interface{}, error) {
+	status := r.getStatus()
+	if status.LastSuccess.IsZero() {
+		return status, errNotRegistered
+	}
+	if status.ConsecutiveFailures > 0 {
+		return status, errRegistrationFailing
+	}
+	return status, nil
+}
+
+func (r *registrationSupervisor) getStatus() registrationStatus {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.status
+}
+
+// registrationSupervisors supervise each of the webhook registrations.
+type registrationSupervisors []*registrationSupervisor
+
+func (rs registrationSupervisors) Start() {
+	for _, r := range rs {
+		r.Start()
+	}
+}
+
+func (rs registrationSupervisors) Stop() {
+	for _, r := range rs {
+		r.Stop()
+	}
+}
+
+func (rs registrationSupervisors) Unregister() {
+	for _, r := range rs {
+		r.Unregister()
+	}
+}
+
+// Failed receives an error for each supervisor that gives up on registering.
+func (rs registrationSupervisors) Failed() <-chan error {
+	failed := make(chan error, len(rs))
+	for _, r := range rs {
+		go func(r *registrationSupervisor) {
+			<-r.stopped
+			select {
+			case err := <-r.failed:
+				failed <- err
+			default:
+			}
+		}(r)
+	}
+	return failed
+}
+
+// responseRecorder is a transport that remembers the status code of the
+// latest response, since the registerer doesn't return it.
+type responseRecorder struct {
+	next http.RoundTripper
+	code int32
+}
+
+func newResponseRecorder(next http.RoundTripper) *responseRecorder {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &responseRecorder{next: next}
+}
+
+func (t *responseRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	code := 0
+	if err == nil {
+		code = resp.StatusCode
+	}
+	atomic.StoreInt32(&t.code, int32(code))
+	return resp, err
+}
+
+// lastStatusCode is the status code of the latest response, or 0 if there
+// was no response.
+func (t *responseRecorder) lastStatusCode() int {
+	if t == nil {
+		return 0
+	}
+	return int(atomic.LoadInt32(&t.code))
+}
+
+// ingestionHealth is whether svalinn can take events, which is reported by
+// every fatal check but the webhook registrations.  They're on the same
+// health endpoint, so an instance that isn't registered shows as unhealthy,
+// but it can still take the events sent to it.
+type ingestionHealth struct {
+	health *health.Health
+}
+
+func (h ingestionHealth) Failed() bool {
+	states, _, err := h.health.State()
+	if err != nil {
+		return false
+	}
+	for name, state := range states {
+		if strings.HasPrefix(name, registrationCheckName) {
+			continue
+		}
+		if state.Fatal && state.Status == "failed" {
+			return true
+		}
+	}
+	return false
+}
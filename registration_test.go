@@ -0,0 +1,363 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/InVisionApp/go-health/v2"
+	"github.com/cenkalti/backoff/v3"
+	"github.com/go-kit/kit/metrics/generic"
+	"github.com/go-kit/kit/metrics/provider"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/v2/logging"
+	"github.com/xmidt-org/wrp-listener/webhookClient"
+)
+
+func TestNewRegistrationSupervisor(t *testing.T) {
+	tests := []struct {
+		description         string
+		interval            time.Duration
+		config              RegistrationRetryConfig
+		expectedFailFast    bool
+		expectedMaxInterval time.Duration
+		expectedMaxElapsed  time.Duration
+		expectedErr         error
+	}{
+		{
+			description:         "Defaults",
+			interval:            time.Hour,
+			expectedMaxInterval: defaultRetryMaxInterval,
+		},
+		{
+			description:         "Fail Fast",
+			interval:            time.Hour,
+			config:              RegistrationRetryConfig{FailurePolicy: "failFast"},
+			expectedFailFast:    true,
+			expectedMaxInterval: defaultRetryMaxInterval,
+			expectedMaxElapsed:  defaultFailFastElapsedTime,
+		},
+		{
+			description: "Keep Retrying Ignores Max Elapsed Time",
+			interval:    time.Hour,
+			config: RegistrationRetryConfig{
+				FailurePolicy: "keepRetrying",
+				Backoff:       backoff.ExponentialBackOff{MaxElapsedTime: time.Minute},
+			},
+			expectedMaxInterval: defaultRetryMaxInterval,
+		},
+		{
+			description:         "Max Interval Capped By Registration Interval",
+			interval:            30 * time.Second,
+			expectedMaxInterval: 30 * time.Second,
+		},
+		{
+			description: "Unknown Policy",
+			interval:    time.Hour,
+			config:      RegistrationRetryConfig{FailurePolicy: "sometimes"},
+			expectedErr: errUnknownFailurePolicy,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			r, err := newRegistrationSupervisor(supervisorConfig{
+				registerer: new(mockRegisterer),
+				interval:   tc.interval,
+				retry:      tc.config,
+				measures:   webhookClient.NewMeasures(provider.NewDiscardProvider()),
+				logger:     logging.NewTestLogger(nil, t),
+			})
+			if tc.expectedErr != nil {
+				require.NotNil(t, err)
+				assert.Contains(err.Error(), tc.expectedErr.Error())
+				return
+			}
+			require.Nil(t, err)
+			assert.Equal(tc.expectedFailFast, r.failFast)
+			assert.Equal(tc.expectedMaxInterval, r.backoff.MaxInterval)
+			assert.Equal(tc.expectedMaxElapsed, r.backoff.MaxElapsedTime)
+		})
+	}
+}
+
+func TestRegistrationSupervisor(t *testing.T) {
+	errRegister := errors.New("test register error")
+	fastBackoff := backoff.ExponentialBackOff{
+		InitialInterval: time.Millisecond,
+		MaxInterval:     5 * time.Millisecond,
+		Multiplier:      2,
+		MaxElapsedTime:  50 * time.Millisecond,
+	}
+	newSupervisor := func(t *testing.T, registerer *mockRegisterer, policy string) *registrationSupervisor {
+		r, err := newRegistrationSupervisor(supervisorConfig{
+			registerer: registerer,
+			interval:   time.Hour,
+			retry:      RegistrationRetryConfig{FailurePolicy: policy, Backoff: fastBackoff},
+			measures:   webhookClient.NewMeasures(provider.NewDiscardProvider()),
+			logger:     logging.NewTestLogger(nil, t),
+		})
+		require.Nil(t, err)
+		return r
+	}
+
+	t.Run("Not Registered Yet", func(t *testing.T) {
+		r := newSupervisor(t, new(mockRegisterer), "")
+		_, err := r.Status()
+		assert.Equal(t, errNotRegistered, err)
+	})
+
+	t.Run("Retries Until Registered", func(t *testing.T) {
+		assert := assert.New(t)
+		registerer := new(mockRegisterer)
+		registerer.On("Register").Return(errRegister).Times(3)
+		registered := make(chan struct{})
+		registerer.On("Register").Return(nil).Once().Run(func(mock.Arguments) { close(registered) })
+		r := newSupervisor(t, registerer, keepRetryingPolicy)
+		r.Start()
+		select {
+		case <-registered:
+		case <-time.After(5 * time.Second):
+			t.Fatal("webhook was never registered")
+		}
+		r.Stop()
+		registerer.AssertExpectations(t)
+
+		status, err := r.Status()
+		assert.Nil(err)
+		s := status.(registrationStatus)
+		assert.Zero(s.ConsecutiveFailures)
+		assert.Empty(s.LastError)
+		assert.False(s.LastSuccess.IsZero())
+	})
+
+	t.Run("Fail Fast Gives Up", func(t *testing.T) {
+		assert := assert.New(t)
+		registerer := new(mockRegisterer)
+		registerer.On("Register").Return(errRegister)
+		r := newSupervisor(t, registerer, failFastPolicy)
+		r.Start()
+		select {
+		case err := <-r.Failed():
+			assert.Contains(err.Error(), errRegistrationGaveUp.Error())
+		case <-time.After(5 * time.Second):
+			t.Fatal("supervisor never gave up")
+		}
+		r.Stop()
+
+		status, err := r.Status()
+		assert.Equal(errNotRegistered, err)
+		s := status.(registrationStatus)
+		assert.Equal(errRegister.Error(), s.LastError)
+		assert.True(s.ConsecutiveFailures > 0)
+	})
+
+	t.Run("Register Now", func(t *testing.T) {
+		registerer := new(mockRegisterer)
+		registerer.On("Register").Return(nil).Once()
+		registered := make(chan struct{})
+		registerer.On("Register").Return(nil).Once().Run(func(mock.Arguments) { close(registered) })
+		r := newSupervisor(t, registerer, "")
+		r.Start()
+		r.RegisterNow()
+		select {
+		case <-registered:
+		case <-time.After(5 * time.Second):
+			t.Fatal("webhook wasn't registered again")
+		}
+		r.Stop()
+		registerer.AssertExpectations(t)
+	})
+
+	t.Run("Failing After Registered", func(t *testing.T) {
+		assert := assert.New(t)
+		registerer := new(mockRegisterer)
+		registerer.On("Register").Return(nil).Once()
+		registerer.On("Register").Return(errRegister).Once()
+		r := newSupervisor(t, registerer, failFastPolicy)
+		assert.True(r.register())
+		assert.False(r.failFast)
+		assert.Zero(r.backoff.MaxElapsedTime)
+		assert.False(r.register())
+		_, err := r.Status()
+		assert.Equal(errRegistrationFailing, err)
+	})
+}
+
+func TestRegistrationSupervisorsFailed(t *testing.T) {
+	registered, failing := new(mockRegisterer), new(mockRegisterer)
+	registered.On("Register").Return(nil)
+	failing.On("Register").Return(errors.New("test register error"))
+	var rs registrationSupervisors
+	for _, registerer := range []*mockRegisterer{registered, failing} {
+		r, err := newRegistrationSupervisor(supervisorConfig{
+			registerer: registerer,
+			interval:   time.Hour,
+			retry: RegistrationRetryConfig{
+				FailurePolicy: failFastPolicy,
+				Backoff:       backoff.ExponentialBackOff{InitialInterval: time.Millisecond, MaxElapsedTime: 20 * time.Millisecond},
+			},
+			measures: webhookClient.NewMeasures(provider.NewDiscardProvider()),
+			logger:   logging.NewTestLogger(nil, t),
+		})
+		require.Nil(t, err)
+		rs = append(rs, r)
+	}
+	failed := rs.Failed()
+	rs.Start()
+	select {
+	case err := <-failed:
+		assert.Contains(t, err.Error(), errRegistrationGaveUp.Error())
+	case <-time.After(5 * time.Second):
+		t.Fatal("supervisor never gave up")
+	}
+	rs.Stop()
+}
+
+type mockUnregisterer struct {
+	mock.Mock
+}
+
+func (u *mockUnregisterer) Unregister() error {
+	args := u.Called()
+	return args.Error(0)
+}
+
+func TestRegistrationSupervisorUnregister(t *testing.T) {
+	registerer, unregisterer := new(mockRegisterer), new(mockUnregisterer)
+	r, err := newRegistrationSupervisor(supervisorConfig{
+		registerer:   registerer,
+		unregisterer: unregisterer,
+		interval:     time.Hour,
+		measures:     webhookClient.NewMeasures(provider.NewDiscardProvider()),
+		logger:       logging.NewTestLogger(nil, t),
+	})
+	require.Nil(t, err)
+
+	// nothing to remove before the webhook is registered.
+	r.Unregister()
+	unregisterer.AssertNotCalled(t, "Unregister")
+
+	registerer.On("Register").Return(nil).Once()
+	unregisterer.On("Unregister").Return(nil).Once()
+	assert.True(t, r.register())
+	r.Unregister()
+	unregisterer.AssertExpectations(t)
+}
+
+func TestRegistrationSupervisorSinceSuccess(t *testing.T) {
+	assert := assert.New(t)
+	gauge := generic.NewGauge("test")
+	registerer := new(mockRegisterer)
+	registerer.On("Register").Return(nil).Once()
+	r, err := newRegistrationSupervisor(supervisorConfig{
+		registerer:   registerer,
+		interval:     time.Hour,
+		measures:     webhookClient.NewMeasures(provider.NewDiscardProvider()),
+		sinceSuccess: gauge,
+		logger:       logging.NewTestLogger(nil, t),
+	})
+	require.Nil(t, err)
+	now := r.started.Add(time.Minute)
+	r.now = func() time.Time { return now }
+
+	// before registering, the time is since the supervisor was created.
+	r.updateSinceSuccess()
+	assert.Equal(time.Minute.Seconds(), gauge.Value())
+
+	assert.True(r.register())
+	now = now.Add(10 * time.Second)
+	r.updateSinceSuccess()
+	assert.Equal((10 * time.Second).Seconds(), gauge.Value())
+}
+
+func TestResponseRecorder(t *testing.T) {
+	assert := assert.New(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	var nilRecorder *responseRecorder
+	assert.Zero(nilRecorder.lastStatusCode())
+
+	recorder := newResponseRecorder(nil)
+	client := &http.Client{Transport: recorder}
+	resp, err := client.Get(server.URL)
+	require.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(http.StatusForbidden, recorder.lastStatusCode())
+
+	// no response resets the code.
+	server.Close()
+	_, err = client.Get(server.URL)
+	assert.NotNil(err)
+	assert.Zero(recorder.lastStatusCode())
+}
+
+type testCheck struct {
+	err error
+}
+
+func (c testCheck) Status() (interface{}, error) {
+	return nil, c.err
+}
+
+func TestIngestionHealth(t *testing.T) {
+	tests := []struct {
+		description    string
+		dbErr          error
+		expectedFailed bool
+	}{
+		{
+			description: "Only Registration Failing",
+		},
+		{
+			description:    "Database Failing",
+			dbErr:          errors.New("db is down"),
+			expectedFailed: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			h := health.New()
+			h.DisableLogging()
+			require.Nil(t, h.AddChecks([]*health.Config{
+				{Name: "db", Checker: testCheck{err: tc.dbErr}, Interval: time.Hour, Fatal: true},
+				{Name: registrationCheckName + "-default", Checker: testCheck{err: errNotRegistered}, Interval: time.Hour, Fatal: true},
+			}))
+			require.Nil(t, h.Start())
+			defer h.Stop()
+			assert.Eventually(func() bool {
+				states, _, _ := h.State()
+				return len(states) == 2
+			}, time.Second, time.Millisecond)
+
+			// the health endpoint still reports the registration.
+			assert.True(h.Failed())
+			assert.Equal(tc.expectedFailed, ingestionHealth{health: h}.Failed())
+		})
+	}
+}
@@ -0,0 +1,205 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"container/list"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/goph/emperror"
+)
+
+const (
+	defaultTimestampHeader = "X-Webpa-Timestamp"
+	defaultNonceHeader     = "X-Webpa-Nonce"
+	defaultReplayWindow    = 5 * time.Minute
+	defaultNonceCacheSize  = 100000
+)
+
+var (
+	errMissingReplayHeaders = errors.New("request is missing the timestamp or nonce header")
+	errInvalidTimestamp     = errors.New("invalid request timestamp")
+	errTimestampOutOfWindow = errors.New("request timestamp is outside of the accepted window")
+	errReusedNonce          = errors.New("request nonce has already been used")
+)
+
+// ReplayConfig sets up rejecting signed requests that are sent more than
+// once.  When enabled, each request needs a timestamp and a nonce header, and
+// both are covered by the signature.
+type ReplayConfig struct {
+	Enabled bool
+
+	// TimestampHeader holds the time the request was signed, in seconds
+	// since the epoch.
+	TimestampHeader string
+
+	// NonceHeader holds a value unique to the request.
+	NonceHeader string
+
+	// Window is how far the timestamp can be from now, in either direction.
+	Window time.Duration
+
+	// NonceCacheSize is the most nonces remembered.  When full, the oldest
+	// nonce is forgotten, so it should hold more than a window's worth of
+	// requests.
+	NonceCacheSize int
+}
+
+// signedHeaders are the replay headers of a request.  The prefix is signed
+// before the body.
+type signedHeaders struct {
+	prefix    []byte
+	nonce     string
+	timestamp time.Time
+}
+
+// replayGuard checks the timestamp and nonce of signed requests.
+type replayGuard struct {
+	timestampHeader string
+	nonceHeader     string
+	window          time.Duration
+	nonces          *nonceCache
+	now             func() time.Time
+	rejected        metrics.Counter
+}
+
+func newReplayGuard(config ReplayConfig, rejected metrics.Counter) *replayGuard {
+	if !config.Enabled {
+		return nil
+	}
+	r := &replayGuard{
+		timestampHeader: config.TimestampHeader,
+		nonceHeader:     config.NonceHeader,
+		window:          config.Window,
+		now:             time.Now,
+		rejected:        rejected,
+	}
+	if r.timestampHeader == "" {
+		r.timestampHeader = defaultTimestampHeader
+	}
+	if r.nonceHeader == "" {
+		r.nonceHeader = defaultNonceHeader
+	}
+	if r.window <= 0 {
+		r.window = defaultReplayWindow
+	}
+	size := config.NonceCacheSize
+	if size <= 0 {
+		size = defaultNonceCacheSize
+	}
+	r.nonces = newNonceCache(size)
+	return r
+}
+
+// signedHeaders gets the timestamp and nonce from the request and checks that
+// the timestamp is within the window.  The values returned are signed along
+// with the body.
+func (r *replayGuard) signedHeaders(req *http.Request) (signedHeaders, error) {
+	timestamp, nonce := req.Header.Get(r.timestampHeader), req.Header.Get(r.nonceHeader)
+	if timestamp == "" || nonce == "" {
+		r.reject(missingHeadersReason)
+		return signedHeaders{}, errMissingReplayHeaders
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		r.reject(invalidTimestampReason)
+		return signedHeaders{}, emperror.With(errInvalidTimestamp, "timestamp", timestamp)
+	}
+	signedAt := time.Unix(seconds, 0)
+	diff := r.now().Sub(signedAt)
+	if diff > r.window || diff < -r.window {
+		r.reject(expiredTimestampReason)
+		return signedHeaders{}, emperror.With(errTimestampOutOfWindow, "timestamp", timestamp)
+	}
+	return signedHeaders{
+		prefix:    []byte(timestamp + "\n" + nonce + "\n"),
+		nonce:     nonce,
+		timestamp: signedAt,
+	}, nil
+}
+
+// useNonce records the nonce of a request with a valid signature, failing if
+// it has been seen before.  The nonce is remembered until the request's
+// timestamp is outside of the window.
+func (r *replayGuard) useNonce(nonce string, timestamp time.Time) error {
+	if !r.nonces.add(nonce, r.now(), timestamp.Add(r.window)) {
+		r.reject(reusedNonceReason)
+		return emperror.With(errReusedNonce, "nonce", nonce)
+	}
+	return nil
+}
+
+func (r *replayGuard) reject(reason string) {
+	r.rejected.With(reasonLabel, reason).Add(1.0)
+}
+
+// nonceCache remembers nonces until they expire, holding no more than its
+// size.
+type nonceCache struct {
+	lock   sync.Mutex
+	size   int
+	order  *list.List
+	nonces map[string]*list.Element
+}
+
+type nonceEntry struct {
+	nonce   string
+	expires time.Time
+}
+
+func newNonceCache(size int) *nonceCache {
+	return &nonceCache{
+		size:   size,
+		order:  list.New(),
+		nonces: make(map[string]*list.Element, size),
+	}
+}
+
+// add records the nonce and returns true, or returns false if the nonce is
+// already recorded and hasn't expired.
+func (c *nonceCache) add(nonce string, now time.Time, expires time.Time) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// expired nonces are mostly at the front, since they're added in about
+	// the order they were signed.
+	for e := c.order.Front(); e != nil && !e.Value.(nonceEntry).expires.After(now); e = c.order.Front() {
+		c.remove(e)
+	}
+	if e, ok := c.nonces[nonce]; ok {
+		if e.Value.(nonceEntry).expires.After(now) {
+			return false
+		}
+		c.remove(e)
+	}
+	if c.order.Len() >= c.size {
+		c.remove(c.order.Front())
+	}
+	c.nonces[nonce] = c.order.PushBack(nonceEntry{nonce: nonce, expires: expires})
+	return true
+}
+
+func (c *nonceCache) remove(e *list.Element) {
+	c.order.Remove(e)
+	delete(c.nonces, e.Value.(nonceEntry).nonce)
+}
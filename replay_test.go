@@ -0,0 +1,165 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/v2/xmetrics/xmetricstest"
+)
+
+func TestNonceCache(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Now()
+	c := newNonceCache(2)
+
+	assert.True(c.add("a", now, now.Add(time.Minute)))
+	assert.False(c.add("a", now, now.Add(time.Minute)))
+	assert.True(c.add("b", now, now.Add(time.Minute)))
+
+	// the cache is full, so the oldest nonce is forgotten.
+	assert.True(c.add("c", now, now.Add(time.Minute)))
+	assert.Equal(2, c.order.Len())
+	assert.True(c.add("a", now, now.Add(time.Minute)))
+
+	// expired nonces can be used again.
+	later := now.Add(2 * time.Minute)
+	assert.True(c.add("c", later, later.Add(time.Minute)))
+	assert.Equal(1, c.order.Len())
+}
+
+func TestReplayProtection(t *testing.T) {
+	const secret = "super secret"
+	body := []byte("test body")
+	sign := func(timestamp string, nonce string) string {
+		h := hmac.New(sha256.New, []byte(secret))
+		h.Write([]byte(timestamp + "\n" + nonce + "\n"))
+		h.Write(body)
+		return "Sha256=" + hex.EncodeToString(h.Sum(nil))
+	}
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	old := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+
+	type request struct {
+		timestamp    string
+		nonce        string
+		signature    string
+		expectedCode int
+	}
+	tests := []struct {
+		description    string
+		requests       []request
+		expectedReason string
+	}{
+		{
+			description: "Success",
+			requests: []request{
+				{timestamp: now, nonce: "abc", signature: sign(now, "abc"), expectedCode: http.StatusOK},
+				{timestamp: now, nonce: "def", signature: sign(now, "def"), expectedCode: http.StatusOK},
+			},
+		},
+		{
+			description: "Replayed Request",
+			requests: []request{
+				{timestamp: now, nonce: "abc", signature: sign(now, "abc"), expectedCode: http.StatusOK},
+				{timestamp: now, nonce: "abc", signature: sign(now, "abc"), expectedCode: http.StatusUnauthorized},
+			},
+			expectedReason: reusedNonceReason,
+		},
+		{
+			description: "Missing Headers",
+			requests: []request{
+				{signature: sign("", ""), expectedCode: http.StatusUnauthorized},
+			},
+			expectedReason: missingHeadersReason,
+		},
+		{
+			description: "Invalid Timestamp",
+			requests: []request{
+				{timestamp: "yesterday", nonce: "abc", signature: sign("yesterday", "abc"), expectedCode: http.StatusUnauthorized},
+			},
+			expectedReason: invalidTimestampReason,
+		},
+		{
+			description: "Old Timestamp",
+			requests: []request{
+				{timestamp: old, nonce: "abc", signature: sign(old, "abc"), expectedCode: http.StatusUnauthorized},
+			},
+			expectedReason: expiredTimestampReason,
+		},
+		{
+			description: "Headers Not Signed",
+			requests: []request{
+				{timestamp: now, nonce: "abc", signature: sign(now, "other"), expectedCode: http.StatusUnauthorized},
+				// the nonce wasn't used up by the request with a bad signature.
+				{timestamp: now, nonce: "abc", signature: sign(now, "abc"), expectedCode: http.StatusOK},
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			p := xmetricstest.NewProvider(nil, Metrics)
+			config := SecretConfig{
+				Header:    "X-Webpa-Signature",
+				Delimiter: "=",
+				Algorithm: "sha256",
+				Replay: ReplayConfig{
+					Enabled: true,
+					Window:  time.Minute,
+				},
+			}
+			constructor, err := newSignatureConstructor(config, secret, config.Rotation, NewMeasures(p))
+			require.Nil(t, err)
+			handler := constructor(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			for _, r := range tc.requests {
+				request := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+				request.Header.Set("X-Webpa-Signature", r.signature)
+				if r.timestamp != "" {
+					request.Header.Set(defaultTimestampHeader, r.timestamp)
+				}
+				if r.nonce != "" {
+					request.Header.Set(defaultNonceHeader, r.nonce)
+				}
+				rr := httptest.NewRecorder()
+				handler.ServeHTTP(rr, request)
+				assert.Equal(r.expectedCode, rr.Code)
+			}
+			for _, reason := range []string{missingHeadersReason, invalidTimestampReason, expiredTimestampReason, reusedNonceReason} {
+				expected := 0.0
+				if reason == tc.expectedReason {
+					expected = 1.0
+				}
+				p.Assert(t, ReplayCounter, reasonLabel, reason)(xmetricstest.Value(expected))
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+)
+
+const (
+	defaultMaxRequestSize = 4 * 1024 * 1024
+)
+
+var (
+	errRequestTooLarge = errors.New("request body is larger than the maximum request size")
+)
+
+// limitedBody is a request body that can't be read past a limit.  Once the
+// limit is reached, reading returns errRequestTooLarge if there is more left
+// in the body.
+type limitedBody struct {
+	body      io.ReadCloser
+	remaining int64
+	read      int64
+	exceeded  bool
+}
+
+func newLimitedBody(body io.ReadCloser, limit int64) *limitedBody {
+	return &limitedBody{
+		body:      body,
+		remaining: limit,
+	}
+}
+
+func (l *limitedBody) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		// read one more byte to find out if the body is larger than the limit.
+		var b [1]byte
+		n, err := l.body.Read(b[:])
+		if n > 0 || err == errRequestTooLarge {
+			l.exceeded = true
+			return 0, errRequestTooLarge
+		}
+		return 0, err
+	}
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.body.Read(p)
+	l.remaining -= int64(n)
+	l.read += int64(n)
+	if err == errRequestTooLarge {
+		l.exceeded = true
+	}
+	return n, err
+}
+
+func (l *limitedBody) Close() error {
+	return l.body.Close()
+}
+
+// LimitRequestBody rejects requests that say they are larger than the max size
+// and keeps the rest from being read past it, so that nothing reading the body
+// before the handler can run out of memory.
+func LimitRequestBody(maxSize int64) func(delegate http.Handler) http.Handler {
+	return func(delegate http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				if r.ContentLength > maxSize {
+					w.WriteHeader(http.StatusRequestEntityTooLarge)
+					return
+				}
+				if r.Body != nil {
+					r.Body = newLimitedBody(r.Body, maxSize)
+				}
+				delegate.ServeHTTP(w, r)
+			})
+	}
+}
@@ -0,0 +1,136 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimitedBody(t *testing.T) {
+	tests := []struct {
+		description      string
+		body             string
+		limit            int64
+		expectedBody     string
+		expectedErr      error
+		expectedExceeded bool
+	}{
+		{
+			description:  "Under Limit",
+			body:         "abc",
+			limit:        5,
+			expectedBody: "abc",
+		},
+		{
+			description:  "At Limit",
+			body:         "abcde",
+			limit:        5,
+			expectedBody: "abcde",
+		},
+		{
+			description:      "Over Limit",
+			body:             "abcdef",
+			limit:            5,
+			expectedBody:     "abcde",
+			expectedErr:      errRequestTooLarge,
+			expectedExceeded: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			body := newLimitedBody(ioutil.NopCloser(strings.NewReader(tc.body)), tc.limit)
+			b, err := ioutil.ReadAll(body)
+			assert.Equal(tc.expectedErr, err)
+			assert.Equal(tc.expectedBody, string(b))
+			assert.Equal(tc.expectedExceeded, body.exceeded)
+			assert.Equal(int64(len(tc.expectedBody)), body.read)
+			assert.Nil(body.Close())
+		})
+	}
+}
+
+func TestLimitedBodyNested(t *testing.T) {
+	assert := assert.New(t)
+	inner := newLimitedBody(ioutil.NopCloser(strings.NewReader("abcdef")), 5)
+	outer := newLimitedBody(inner, 5)
+	_, err := ioutil.ReadAll(outer)
+	assert.Equal(errRequestTooLarge, err)
+	assert.True(outer.exceeded)
+}
+
+func TestLimitRequestBody(t *testing.T) {
+	tests := []struct {
+		description    string
+		body           string
+		contentLength  int64
+		expectedCalled bool
+		expectedBody   string
+		expectedErr    error
+		expectedCode   int
+	}{
+		{
+			description:    "Success",
+			body:           "abc",
+			contentLength:  3,
+			expectedCalled: true,
+			expectedBody:   "abc",
+			expectedCode:   http.StatusOK,
+		},
+		{
+			description:   "Content Length Too Large",
+			body:          "abcdef",
+			contentLength: 6,
+			expectedCode:  http.StatusRequestEntityTooLarge,
+		},
+		{
+			description:    "Unknown Content Length Too Large",
+			body:           "abcdef",
+			contentLength:  -1,
+			expectedCalled: true,
+			expectedBody:   "abcde",
+			expectedErr:    errRequestTooLarge,
+			expectedCode:   http.StatusOK,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			called := false
+			delegate := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				b, err := ioutil.ReadAll(r.Body)
+				assert.Equal(tc.expectedErr, err)
+				assert.Equal(tc.expectedBody, string(b))
+			})
+			request := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(tc.body))
+			request.ContentLength = tc.contentLength
+			rr := httptest.NewRecorder()
+			LimitRequestBody(5)(delegate).ServeHTTP(rr, request)
+			assert.Equal(tc.expectedCalled, called)
+			assert.Equal(tc.expectedCode, rr.Code)
+		})
+	}
+}
@@ -43,7 +43,7 @@ func (r *RequestParser) createRecord(req wrp.Message, rule *rules.Rule, eventTyp
 	}
 
 	if reason, ok := r.rc.blacklist.InList(record.DeviceID); ok {
-		return emptyRecord, blackListReason, emperror.With(errBlacklist, "reason", reason)
+		return emptyRecord, blackListReason, emperror.With(ErrBlacklisted, "reason", reason)
 	}
 
 	// verify wrp is the right type
@@ -52,14 +52,18 @@ func (r *RequestParser) createRecord(req wrp.Message, rule *rules.Rule, eventTyp
 	}
 
 	msg := req
+	policy := r.rc.policies.FindPolicy(req.PartnerIDs)
 	var reason string
-	record.BirthDate, record.DeathDate, reason, err = getValidBirthDeathDates(r.rc.currTime, msg.Payload, rule, r.config.DefaultTTL)
+	record.BirthDate, record.DeathDate, reason, err = getValidBirthDeathDates(r.rc.currTime, msg.Payload, rule, policy, r.config.DefaultTTL)
 	if err != nil {
 		return emptyRecord, reason, err
 	}
 
 	// store the payload if we are supposed to and it's not too big
 	storePayload := (rule != nil && rule.StorePayload()) || false
+	if policy != nil && !policy.PayloadAllowed() {
+		storePayload = false
+	}
 	if !storePayload || len(msg.Payload) > r.config.PayloadMaxSize {
 		msg.Payload = nil
 	}
@@ -69,7 +73,11 @@ func (r *RequestParser) createRecord(req wrp.Message, rule *rules.Rule, eventTyp
 	if err != nil {
 		return emptyRecord, parseFailReason, emperror.WrapWith(err, "failed to marshal metadata to determine size", "metadata", msg.Metadata, "full message", req)
 	}
-	if len(marshaledMetadata) > r.config.MetadataMaxSize {
+	metadataMaxSize := r.config.MetadataMaxSize
+	if policy != nil && policy.MetadataMaxSize() > 0 {
+		metadataMaxSize = policy.MetadataMaxSize()
+	}
+	if len(marshaledMetadata) > metadataMaxSize {
 		msg.Metadata = make(map[string]string)
 		msg.Metadata["error"] = "metadata provided exceeds size limit - too big to store"
 	}
@@ -110,7 +118,7 @@ func parseDeviceID(eventType db.EventType, req wrp.Message) (string, error) {
 	return strings.ToLower(req.Source), nil
 }
 
-func getValidBirthDeathDates(currTime func() time.Time, payload []byte, rule *rules.Rule, defaultTTL time.Duration) (int64, int64, string, error) {
+func getValidBirthDeathDates(currTime func() time.Time, payload []byte, rule *rules.Rule, policy *rules.PartnerPolicy, defaultTTL time.Duration) (int64, int64, string, error) {
 	now := currTime()
 	birthDate, ok := getBirthDate(payload)
 	if !ok {
@@ -123,6 +131,13 @@ func getValidBirthDeathDates(currTime func() time.Time, payload []byte, rule *ru
 	if rule != nil && rule.TTL() != 0 {
 		ttl = rule.TTL()
 	}
+	if policy != nil {
+		var ruleTTL time.Duration
+		if rule != nil {
+			ruleTTL = rule.TTL()
+		}
+		ttl = policy.TTL(ruleTTL, defaultTTL)
+	}
 	deathDate := birthDate.Add(ttl)
 	if now.After(deathDate) {
 		return 0, 0, expiredReason, emperror.WrapWith(errExpired, "event is already expired", "deathdate", deathDate.String())
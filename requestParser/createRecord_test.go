@@ -30,6 +30,7 @@ func TestCreateRecord(t *testing.T) {
 		inBlacklist      bool
 		maxPayloadSize   int
 		maxMetadataSize  int
+		policies         []rules.PartnerPolicyConfig
 		encryptCalled    bool
 		encryptErr       error
 		expectedDeviceID string
@@ -65,6 +66,32 @@ func TestCreateRecord(t *testing.T) {
 			blacklistCalled: true,
 			encryptCalled:   true,
 		},
+		{
+			description:      "Success Partner Policy Limits",
+			req:              goodEvent,
+			expectedDeviceID: "test",
+			expectedEvent: wrp.Message{
+				Source:          goodEvent.Source,
+				Destination:     goodEvent.Destination,
+				PartnerIDs:      goodEvent.PartnerIDs,
+				TransactionUUID: goodEvent.TransactionUUID,
+				Type:            goodEvent.Type,
+				Payload:         nil,
+				Metadata:        map[string]string{"error": "metadata provided exceeds size limit - too big to store"},
+			},
+			storePayload:    true,
+			blacklistCalled: true,
+			encryptCalled:   true,
+			maxMetadataSize: 500,
+			maxPayloadSize:  500,
+			policies: []rules.PartnerPolicyConfig{
+				{
+					PartnerID:       "test2",
+					DisablePayload:  true,
+					MetadataMaxSize: 5,
+				},
+			},
+		},
 		{
 			description: "Blacklist Error",
 			req: wrp.Message{
@@ -74,7 +101,7 @@ func TestCreateRecord(t *testing.T) {
 			inBlacklist:     true,
 			blacklistCalled: true,
 			expectedReason:  blackListReason,
-			expectedErr:     errBlacklist,
+			expectedErr:     ErrBlacklisted,
 		},
 		{
 			description: "Unexpected WRP Type Error",
@@ -137,11 +164,14 @@ func TestCreateRecord(t *testing.T) {
 			if tc.blacklistCalled {
 				mblacklist.On("InList", mock.Anything).Return("", tc.inBlacklist).Once()
 			}
+			policies, err := rules.NewPartnerPolicies(tc.policies)
+			assert.Nil(err)
 
 			handler := RequestParser{
 				rc: RecordConfig{
 					encrypter: encrypter,
 					blacklist: mblacklist,
+					policies:  policies,
 					currTime:  timeFunc,
 				},
 
@@ -247,12 +277,24 @@ func TestGetValidBirthDeathDates(t *testing.T) {
 	testassert.Nil(err)
 	rule, err := r.FindRule(" ")
 	testassert.Nil(err)
+	policies, err := rules.NewPartnerPolicies([]rules.PartnerPolicyConfig{
+		{
+			PartnerID:  "default",
+			DefaultTTL: 3 * time.Hour,
+		},
+		{
+			PartnerID: "capped",
+			MaxTTL:    30 * time.Minute,
+		},
+	})
+	testassert.Nil(err)
 
 	tests := []struct {
 		description       string
 		fakeNow           time.Time
 		payload           []byte
 		rule              *rules.Rule
+		policy            *rules.PartnerPolicy
 		expectedBirthDate int64
 		expectedDeathDate int64
 		expectedReason    string
@@ -280,6 +322,32 @@ func TestGetValidBirthDeathDates(t *testing.T) {
 			expectedBirthDate: goodTime.UnixNano(),
 			expectedDeathDate: goodTime.Add(2 * time.Hour).UnixNano(),
 		},
+		{
+			description:       "Success with Partner Default",
+			fakeNow:           goodTime,
+			payload:           goodEvent.Payload,
+			policy:            policies.FindPolicy([]string{"default"}),
+			expectedBirthDate: goodTime.UnixNano(),
+			expectedDeathDate: goodTime.Add(3 * time.Hour).UnixNano(),
+		},
+		{
+			description:       "Success with Rule over Partner Default",
+			fakeNow:           goodTime,
+			payload:           goodEvent.Payload,
+			rule:              rule,
+			policy:            policies.FindPolicy([]string{"default"}),
+			expectedBirthDate: goodTime.UnixNano(),
+			expectedDeathDate: goodTime.Add(2 * time.Hour).UnixNano(),
+		},
+		{
+			description:       "Success with Rule Capped by Partner Max",
+			fakeNow:           goodTime,
+			payload:           goodEvent.Payload,
+			rule:              rule,
+			policy:            policies.FindPolicy([]string{"capped"}),
+			expectedBirthDate: goodTime.UnixNano(),
+			expectedDeathDate: goodTime.Add(30 * time.Minute).UnixNano(),
+		},
 		{
 			description:    "Future Birthdate Error",
 			fakeNow:        currTime.Add(-5 * time.Hour),
@@ -302,7 +370,7 @@ func TestGetValidBirthDeathDates(t *testing.T) {
 			currTime := func() time.Time {
 				return tc.fakeNow
 			}
-			b, d, reason, err := getValidBirthDeathDates(currTime, tc.payload, tc.rule, time.Hour)
+			b, d, reason, err := getValidBirthDeathDates(currTime, tc.payload, tc.rule, tc.policy, time.Hour)
 			assert.Equal(tc.expectedBirthDate, b, "birth date mismatch")
 			assert.Equal(tc.expectedDeathDate, d, "death date mismatch")
 			assert.Equal(tc.expectedReason, reason)
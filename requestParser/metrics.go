@@ -29,11 +29,15 @@ const (
 	ParsingQueueDepth    = "parsing_queue_depth"
 	DroppedEventsCounter = "dropped_events_count"
 	EventCounter         = "event_count"
+
+	PartitionQueueDepth           = "parsing_partition_queue_depth"
+	PartitionDroppedEventsCounter = "partition_dropped_events_count"
 )
 
 const (
 	partnerIDLabel         = "partner_id"
 	eventDestLabel         = "event_destination"
+	partitionLabel         = "partition"
 	reasonLabel            = "reason"
 	blackListReason        = "blacklist"
 	parseFailReason        = "parsing_failed"
@@ -42,6 +46,8 @@ const (
 	invalidBirthdateReason = "birthdate_too_far_in_future"
 	expiredReason          = "deathdate_has_already_passed"
 	queueFullReason        = "queue_full"
+	shedReason             = "shed_for_higher_priority"
+	highWaterMarkReason    = "queue_over_high_water_mark"
 	insertFailReason       = "inserting_failed"
 )
 
@@ -69,13 +75,27 @@ func Metrics() []xmetrics.Metric {
 			Type:       "counter",
 			LabelNames: []string{partnerIDLabel, eventDestLabel},
 		},
+		{
+			Name:       PartitionQueueDepth,
+			Help:       "The depth of each partition of the parsing queue",
+			Type:       "gauge",
+			LabelNames: []string{partitionLabel},
+		},
+		{
+			Name:       PartitionDroppedEventsCounter,
+			Help:       "The total number of events dropped because their partition was full",
+			Type:       "counter",
+			LabelNames: []string{partitionLabel},
+		},
 	}
 }
 
 type Measures struct {
-	ParsingQueue       metrics.Gauge
-	DroppedEventsCount metrics.Counter
-	EventsCount        metrics.Counter
+	ParsingQueue                metrics.Gauge
+	DroppedEventsCount          metrics.Counter
+	EventsCount                 metrics.Counter
+	PartitionQueue              metrics.Gauge
+	PartitionDroppedEventsCount metrics.Counter
 }
 
 type EventTypeMetrics struct {
@@ -86,8 +106,10 @@ type EventTypeMetrics struct {
 // NewMeasures constructs a Measures given a go-kit metrics Provider
 func NewMeasures(p provider.Provider) *Measures {
 	return &Measures{
-		ParsingQueue:       p.NewGauge(ParsingQueueDepth),
-		DroppedEventsCount: p.NewCounter(DroppedEventsCounter),
-		EventsCount:        p.NewCounter(EventCounter),
+		ParsingQueue:                p.NewGauge(ParsingQueueDepth),
+		DroppedEventsCount:          p.NewCounter(DroppedEventsCounter),
+		EventsCount:                 p.NewCounter(EventCounter),
+		PartitionQueue:              p.NewGauge(PartitionQueueDepth),
+		PartitionDroppedEventsCount: p.NewCounter(PartitionDroppedEventsCounter),
 	}
 }
@@ -4,7 +4,9 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/mock"
+	db "github.com/xmidt-org/codex-db"
 	"github.com/xmidt-org/codex-db/batchInserter"
+	"github.com/xmidt-org/svalinn/rules"
 	"github.com/xmidt-org/voynicrypto"
 )
 
@@ -45,6 +47,39 @@ func (i *mockInserter) Insert(record batchInserter.RecordWithTime) error {
 	return args.Error(0)
 }
 
+type mockPriorityInserter struct {
+	mockInserter
+}
+
+func (i *mockPriorityInserter) InsertWithPriority(record batchInserter.RecordWithTime, priority rules.Priority) error {
+	args := i.Called(record, priority)
+	return args.Error(0)
+}
+
+type mockTargetInserter struct {
+	mockInserter
+}
+
+func (i *mockTargetInserter) InsertToTarget(record batchInserter.RecordWithTime, target string, priority rules.Priority) error {
+	args := i.Called(record, target, priority)
+	return args.Error(0)
+}
+
+// trackingInserter keeps the done func of the record it's tracking, so tests
+// can finish the record's batch.
+type trackingInserter struct {
+	mockInserter
+	done      func(error)
+	untracked bool
+}
+
+func (i *trackingInserter) Track(record db.Record, done func(error)) func() {
+	i.done = done
+	return func() {
+		i.untracked = true
+	}
+}
+
 type mockTimeTracker struct {
 	mock.Mock
 }
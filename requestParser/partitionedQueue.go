@@ -0,0 +1,314 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package requestParser
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goph/emperror"
+	db "github.com/xmidt-org/codex-db"
+	"github.com/xmidt-org/svalinn/rules"
+	"github.com/xmidt-org/wrp-go/v3"
+)
+
+const (
+	PartitionByNone      = ""
+	PartitionByPartner   = "partner"
+	PartitionByEventType = "eventType"
+
+	defaultPartition     = "default"
+	minWeight            = 1
+	defaultMaxPartitions = 100
+)
+
+var (
+	errInvalidPartitionBy = errors.New("invalid partitionBy value")
+	errDuplicatePartition = errors.New("partition is configured more than once")
+	errEmptyPartitionName = errors.New("partition has an empty name")
+)
+
+// PartitionConfig describes one partition of the parsing queue.  Weight is
+// the number of events taken from the partition each scheduling round, and
+// QueueSize is the number of events the partition can hold before events for
+// it are dropped.
+type PartitionConfig struct {
+	Name      string
+	Weight    int
+	QueueSize int
+}
+
+type partition struct {
+	name   string
+	weight int
+	size   int
+	count  int
+	lanes  [rules.HighPriority + 1][]WrpWithTime
+}
+
+// partitionedQueue holds a bounded queue per partition and hands events out
+// in weighted round robin order, so a surge in one partition can only fill
+// that partition's queue.  Keys without a configured partition get their own
+// partition with the default weight and queue size, until there are too many
+// of them, after which they share the default partition.  Each partition has
+// a lane per priority: higher
+// priority events are handed out first, and lower priority events are shed
+// to make room for them when the partition is full.
+type partitionedQueue struct {
+	lock             sync.Mutex
+	partitions       []*partition
+	index            map[string]*partition
+	current          int
+	credits          int
+	stopped          bool
+	notify           chan struct{}
+	drained          *rateTracker
+	defaultQueueSize int
+	created          int
+	maxCreated       int
+}
+
+// newPartitionedQueue creates the configured partitions.  Up to maxCreated
+// more are created as events for other keys arrive.
+func newPartitionedQueue(configs []PartitionConfig, defaultQueueSize int, maxCreated int) (*partitionedQueue, error) {
+	q := &partitionedQueue{
+		index:            make(map[string]*partition, len(configs)+1),
+		notify:           make(chan struct{}, 1),
+		drained:          newRateTracker(time.Now),
+		defaultQueueSize: defaultQueueSize,
+		maxCreated:       maxCreated,
+	}
+	for _, c := range configs {
+		if c.Name == "" {
+			return nil, errEmptyPartitionName
+		}
+		if _, ok := q.index[c.Name]; ok {
+			return nil, emperror.With(errDuplicatePartition, "partition", c.Name)
+		}
+		q.add(c)
+	}
+	if _, ok := q.index[defaultPartition]; !ok {
+		q.add(PartitionConfig{Name: defaultPartition})
+	}
+	q.credits = q.partitions[0].weight
+	return q, nil
+}
+
+// add creates a partition.  The lock must be held once the queue is in use.
+func (q *partitionedQueue) add(c PartitionConfig) *partition {
+	if c.Weight < minWeight {
+		c.Weight = minWeight
+	}
+	if c.QueueSize < defaultMinQueueSize {
+		c.QueueSize = q.defaultQueueSize
+	}
+	p := &partition{
+		name:   c.Name,
+		weight: c.Weight,
+		size:   c.QueueSize,
+	}
+	q.partitions = append(q.partitions, p)
+	q.index[c.Name] = p
+	return p
+}
+
+// partitionFor returns the partition for the key, creating it if there's
+// room for another one, or falling back to the default partition.
+func (q *partitionedQueue) partitionFor(key string) *partition {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if p, ok := q.index[key]; ok {
+		return p
+	}
+	if key == "" || q.created >= q.maxCreated {
+		return q.index[defaultPartition]
+	}
+	q.created++
+	return q.add(PartitionConfig{Name: key})
+}
+
+// has returns whether the key has a configured or created partition.
+func (q *partitionedQueue) has(key string) bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	_, ok := q.index[key]
+	return ok
+}
+
+// push adds the event to the partition without blocking.  If the partition is
+// full, the oldest event with a lower priority is removed and returned so the
+// new event fits.  push returns an error if the event couldn't be added
+// because the partition is full or the queue has been stopped.
+func (q *partitionedQueue) push(p *partition, w WrpWithTime, priority rules.Priority) (*WrpWithTime, error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if q.stopped {
+		return nil, ErrShuttingDown
+	}
+
+	var shed *WrpWithTime
+	if p.count >= p.size {
+		for lower := rules.LowPriority; lower < priority && shed == nil; lower++ {
+			if len(p.lanes[lower]) > 0 {
+				oldest := p.dequeue(lower)
+				shed = &oldest
+			}
+		}
+		if shed == nil {
+			return nil, ErrQueueFull
+		}
+	}
+	p.lanes[priority] = append(p.lanes[priority], w)
+	p.count++
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+	return shed, nil
+}
+
+// next blocks until an event is available and returns it along with the
+// partition it came from.  Once the queue is stopped, next keeps returning
+// the events left in the partitions and then returns false.
+func (q *partitionedQueue) next() (WrpWithTime, *partition, bool) {
+	for {
+		q.lock.Lock()
+		for priority := rules.HighPriority; priority >= rules.LowPriority; priority-- {
+			if w, p, ok := q.pop(priority); ok {
+				q.lock.Unlock()
+				q.drained.mark()
+				return w, p, true
+			}
+		}
+		stopped := q.stopped
+		q.lock.Unlock()
+		if stopped {
+			return WrpWithTime{}, nil, false
+		}
+		<-q.notify
+	}
+}
+
+// pop takes the next event from the lane of the given priority in weighted
+// round robin order.  The lock must be held.
+func (q *partitionedQueue) pop(priority rules.Priority) (WrpWithTime, *partition, bool) {
+	current, credits := q.current, q.credits
+	// check every partition once, including the one we started on after its
+	// credits are refilled.
+	for i := 0; i <= len(q.partitions); i++ {
+		p := q.partitions[q.current]
+		if q.credits > 0 && len(p.lanes[priority]) > 0 {
+			q.credits--
+			return p.dequeue(priority), p, true
+		}
+		q.current = (q.current + 1) % len(q.partitions)
+		q.credits = q.partitions[q.current].weight
+	}
+	// nothing was found, so don't let the scan change whose turn it is.
+	q.current, q.credits = current, credits
+	return WrpWithTime{}, nil, false
+}
+
+// fill returns how full the partition is, from 0 to 1.
+func (q *partitionedQueue) fill(p *partition) float64 {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return float64(p.count) / float64(p.size)
+}
+
+// depth returns the number of events in all of the partitions.
+func (q *partitionedQueue) depth() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	depth := 0
+	for _, p := range q.partitions {
+		depth += p.count
+	}
+	return depth
+}
+
+// drainRate returns the number of events recently taken off the queue per
+// second.
+func (q *partitionedQueue) drainRate() float64 {
+	return q.drained.perSecond()
+}
+
+func (q *partitionedQueue) stop() {
+	q.lock.Lock()
+	q.stopped = true
+	q.lock.Unlock()
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// dequeue removes the oldest event from a lane.  The queue's lock must be
+// held.
+func (p *partition) dequeue(priority rules.Priority) WrpWithTime {
+	lane := p.lanes[priority]
+	w := lane[0]
+	lane[0] = WrpWithTime{}
+	p.lanes[priority] = lane[1:]
+	p.count--
+	return w
+}
+
+func validPartitionBy(partitionBy string) error {
+	switch partitionBy {
+	case PartitionByNone, PartitionByPartner, PartitionByEventType:
+		return nil
+	}
+	return emperror.With(errInvalidPartitionBy, "partitionBy", partitionBy)
+}
+
+// partitionKey determines which partition an event belongs to.  Partitioned
+// by partner, it's the first partner id with a partition, or else the first
+// partner id.
+func (r *RequestParser) partitionKey(msg wrp.Message, rule *rules.Rule) string {
+	switch r.config.PartitionBy {
+	case PartitionByPartner:
+		for _, id := range msg.PartnerIDs {
+			if r.queue.has(id) {
+				return id
+			}
+		}
+		for _, id := range msg.PartnerIDs {
+			if id != "" {
+				return id
+			}
+		}
+	case PartitionByEventType:
+		eventType := db.Default
+		if rule != nil {
+			eventType = db.ParseEventType(rule.EventType())
+		}
+		return strings.ToLower(eventType.String())
+	}
+	return defaultPartition
+}
+
+func rulePriority(rule *rules.Rule) rules.Priority {
+	if rule == nil {
+		return rules.NormalPriority
+	}
+	return rule.Priority()
+}
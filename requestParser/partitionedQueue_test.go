@@ -0,0 +1,461 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package requestParser
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/svalinn/rules"
+	"github.com/xmidt-org/webpa-common/v2/xmetrics/xmetricstest"
+	"github.com/xmidt-org/wrp-go/v3"
+)
+
+func TestNewPartitionedQueue(t *testing.T) {
+	tests := []struct {
+		description        string
+		configs            []PartitionConfig
+		expectedPartitions []partition
+		expectedErr        error
+	}{
+		{
+			description: "Default Only",
+			expectedPartitions: []partition{
+				{name: defaultPartition, weight: 1},
+			},
+		},
+		{
+			description: "Configured Partitions",
+			configs: []PartitionConfig{
+				{Name: "a", Weight: 3, QueueSize: 10},
+				{Name: "b"},
+			},
+			expectedPartitions: []partition{
+				{name: "a", weight: 3},
+				{name: "b", weight: 1},
+				{name: defaultPartition, weight: 1},
+			},
+		},
+		{
+			description: "Configured Default",
+			configs: []PartitionConfig{
+				{Name: defaultPartition, Weight: 2},
+			},
+			expectedPartitions: []partition{
+				{name: defaultPartition, weight: 2},
+			},
+		},
+		{
+			description: "Empty Name Error",
+			configs:     []PartitionConfig{{Weight: 2}},
+			expectedErr: errEmptyPartitionName,
+		},
+		{
+			description: "Duplicate Name Error",
+			configs:     []PartitionConfig{{Name: "a"}, {Name: "a"}},
+			expectedErr: errDuplicatePartition,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			q, err := newPartitionedQueue(tc.configs, 7, 0)
+			if tc.expectedErr != nil {
+				assert.Nil(q)
+				assert.Contains(err.Error(), tc.expectedErr.Error())
+				return
+			}
+			assert.Nil(err)
+			assert.Len(q.partitions, len(tc.expectedPartitions))
+			for i, p := range q.partitions {
+				assert.Equal(tc.expectedPartitions[i].name, p.name)
+				assert.Equal(tc.expectedPartitions[i].weight, p.weight)
+			}
+			assert.Equal(7, q.partitionFor("unknown").size)
+		})
+	}
+}
+
+func TestPartitionedQueueWeightedOrder(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	q, err := newPartitionedQueue([]PartitionConfig{
+		{Name: "heavy", Weight: 2},
+		{Name: "light", Weight: 1},
+	}, 10, 0)
+	require.Nil(err)
+
+	for i := 0; i < 4; i++ {
+		assert.True(push(q, "heavy", WrpWithTime{Message: wrp.Message{Source: "heavy"}}, rules.NormalPriority))
+		assert.True(push(q, "light", WrpWithTime{Message: wrp.Message{Source: "light"}}, rules.NormalPriority))
+	}
+
+	var order []string
+	for i := 0; i < 8; i++ {
+		w, p, ok := q.next()
+		require.True(ok)
+		assert.Equal(p.name, w.Message.Source)
+		order = append(order, p.name)
+	}
+	assert.Equal([]string{"heavy", "heavy", "light", "heavy", "heavy", "light", "light", "light"}, order)
+}
+
+func TestPartitionedQueueFullAndStop(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	q, err := newPartitionedQueue([]PartitionConfig{{Name: "a", QueueSize: 5}}, 5, 0)
+	require.Nil(err)
+
+	for i := 0; i < 5; i++ {
+		assert.True(push(q, "a", WrpWithTime{}, rules.NormalPriority))
+	}
+	assert.False(push(q, "a", WrpWithTime{}, rules.NormalPriority))
+	assert.True(push(q, "b", WrpWithTime{}, rules.NormalPriority))
+
+	q.stop()
+	_, err = q.push(q.partitionFor("a"), WrpWithTime{}, rules.HighPriority)
+	assert.Equal(ErrShuttingDown, err)
+	for i := 0; i < 6; i++ {
+		_, _, ok := q.next()
+		assert.True(ok)
+	}
+	_, _, ok := q.next()
+	assert.False(ok)
+}
+
+func TestPartitionedQueuePriority(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	q, err := newPartitionedQueue(nil, 5, 0)
+	require.Nil(err)
+	p := q.partitionFor(defaultPartition)
+
+	for i, priority := range []rules.Priority{rules.LowPriority, rules.NormalPriority, rules.LowPriority, rules.NormalPriority, rules.NormalPriority} {
+		shed, err := q.push(p, WrpWithTime{Message: wrp.Message{Source: priority.String() + strconv.Itoa(i)}}, priority)
+		assert.Nil(err)
+		assert.Nil(shed)
+	}
+
+	// the queue is full, so the oldest low priority event is shed.
+	shed, err := q.push(p, WrpWithTime{Message: wrp.Message{Source: "high5"}}, rules.HighPriority)
+	assert.Nil(err)
+	require.NotNil(shed)
+	assert.Equal("low0", shed.Message.Source)
+
+	// normal events can shed low priority events, but not other normal ones.
+	shed, err = q.push(p, WrpWithTime{Message: wrp.Message{Source: "normal6"}}, rules.NormalPriority)
+	assert.Nil(err)
+	require.NotNil(shed)
+	assert.Equal("low2", shed.Message.Source)
+	_, err = q.push(p, WrpWithTime{}, rules.NormalPriority)
+	assert.Equal(ErrQueueFull, err)
+
+	var order []string
+	for i := 0; i < 5; i++ {
+		w, _, ok := q.next()
+		require.True(ok)
+		order = append(order, w.Message.Source)
+	}
+	assert.Equal([]string{"high5", "normal1", "normal3", "normal4", "normal6"}, order)
+}
+
+func TestPartitionKey(t *testing.T) {
+	r, err := rules.NewRules([]rules.RuleConfig{
+		{
+			Regex:     ".*/online$",
+			EventType: "State",
+		},
+	})
+	require.Nil(t, err)
+
+	tests := []struct {
+		description string
+		partitionBy string
+		msg         wrp.Message
+		expectedKey string
+	}{
+		{
+			description: "No Partitioning",
+			msg:         wrp.Message{PartnerIDs: []string{"comcast"}},
+			expectedKey: defaultPartition,
+		},
+		{
+			description: "Partner",
+			partitionBy: PartitionByPartner,
+			msg:         wrp.Message{PartnerIDs: []string{"other", "comcast"}},
+			expectedKey: "comcast",
+		},
+		{
+			description: "Unconfigured Partner",
+			partitionBy: PartitionByPartner,
+			msg:         wrp.Message{PartnerIDs: []string{"other"}},
+			expectedKey: "other",
+		},
+		{
+			description: "No Partner",
+			partitionBy: PartitionByPartner,
+			msg:         wrp.Message{},
+			expectedKey: defaultPartition,
+		},
+		{
+			description: "State Event Type",
+			partitionBy: PartitionByEventType,
+			msg:         wrp.Message{Destination: "device-status/mac:112233445566/online"},
+			expectedKey: "state",
+		},
+		{
+			description: "Default Event Type",
+			partitionBy: PartitionByEventType,
+			msg:         wrp.Message{Destination: "device-status/mac:112233445566/reboot"},
+			expectedKey: defaultPartition,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			q, err := newPartitionedQueue([]PartitionConfig{{Name: "comcast"}, {Name: "state"}}, 5, 0)
+			assert.Nil(err)
+			p := RequestParser{
+				config: Config{PartitionBy: tc.partitionBy},
+				queue:  q,
+			}
+			rule, _ := r.FindRule(tc.msg.Destination)
+			assert.Equal(tc.expectedKey, p.partitionKey(tc.msg, rule))
+		})
+	}
+}
+
+func TestPartitionedQueueCreatesPartitions(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	q, err := newPartitionedQueue(nil, 5, 2)
+	require.Nil(err)
+
+	// a burst from one partner fills only its own partition.
+	for i := 0; i < 5; i++ {
+		assert.True(push(q, "noisy", WrpWithTime{Message: wrp.Message{Source: "noisy"}}, rules.NormalPriority))
+	}
+	assert.False(push(q, "noisy", WrpWithTime{}, rules.NormalPriority))
+	assert.True(push(q, "quiet", WrpWithTime{Message: wrp.Message{Source: "quiet"}}, rules.NormalPriority))
+
+	// the quiet partner doesn't wait behind the noisy one's burst.
+	var order []string
+	for i := 0; i < 3; i++ {
+		w, _, ok := q.next()
+		require.True(ok)
+		order = append(order, w.Message.Source)
+	}
+	assert.Equal([]string{"noisy", "quiet", "noisy"}, order)
+
+	// once the max is reached, other partners share the default partition.
+	assert.Equal(defaultPartition, q.partitionFor("third").name)
+	assert.Equal("quiet", q.partitionFor("quiet").name)
+	assert.Len(q.partitions, 3)
+}
+
+func TestParseQueueFull(t *testing.T) {
+	assert := assert.New(t)
+	q, err := newPartitionedQueue([]PartitionConfig{{Name: "comcast", QueueSize: 5}}, 5, 0)
+	assert.Nil(err)
+	p := xmetricstest.NewProvider(nil, Metrics)
+	r := RequestParser{
+		config:   Config{PartitionBy: PartitionByPartner},
+		measures: NewMeasures(p),
+		queue:    q,
+	}
+
+	msg := WrpWithTime{Message: wrp.Message{PartnerIDs: []string{"comcast"}}}
+	for i := 0; i < 5; i++ {
+		assert.Nil(r.Parse(msg))
+	}
+	assert.Equal(ErrQueueFull, r.Parse(msg))
+	assert.Nil(r.Parse(WrpWithTime{}))
+
+	p.Assert(t, ParsingQueueDepth)(xmetricstest.Value(6.0))
+	p.Assert(t, PartitionQueueDepth, partitionLabel, "comcast")(xmetricstest.Value(5.0))
+	p.Assert(t, PartitionQueueDepth, partitionLabel, defaultPartition)(xmetricstest.Value(1.0))
+	p.Assert(t, DroppedEventsCounter, reasonLabel, queueFullReason)(xmetricstest.Value(1.0))
+	p.Assert(t, PartitionDroppedEventsCounter, partitionLabel, "comcast")(xmetricstest.Value(1.0))
+}
+
+func TestParseShed(t *testing.T) {
+	assert := assert.New(t)
+	r, err := rules.NewRules([]rules.RuleConfig{
+		{
+			Regex:    ".*/online$",
+			Priority: "high",
+		},
+		{
+			Regex:    ".*",
+			Priority: "low",
+		},
+	})
+	assert.Nil(err)
+	q, err := newPartitionedQueue(nil, 5, 0)
+	assert.Nil(err)
+	p := xmetricstest.NewProvider(nil, Metrics)
+	mockTimeTracker := new(mockTimeTracker)
+	mockTimeTracker.On("TrackTime", mock.Anything).Once()
+	parser := RequestParser{
+		rc:       RecordConfig{rules: r, timeTracker: mockTimeTracker},
+		measures: NewMeasures(p),
+		queue:    q,
+	}
+
+	var doneErrs []error
+	done := func(err error) {
+		doneErrs = append(doneErrs, err)
+	}
+	for i := 0; i < 5; i++ {
+		assert.Nil(parser.Parse(WrpWithTime{Message: wrp.Message{Destination: "event/reboot"}, Done: done}))
+	}
+	assert.Nil(parser.Parse(WrpWithTime{Message: wrp.Message{Destination: "event/online"}}))
+	assert.Equal(ErrQueueFull, parser.Parse(WrpWithTime{Message: wrp.Message{Destination: "event/reboot"}, Done: done}))
+	// only the event that was shed is done, the rejected one was never accepted.
+	assert.Equal([]error{ErrQueueFull}, doneErrs)
+
+	mockTimeTracker.AssertExpectations(t)
+	p.Assert(t, ParsingQueueDepth)(xmetricstest.Value(5.0))
+	p.Assert(t, DroppedEventsCounter, reasonLabel, shedReason)(xmetricstest.Value(1.0))
+	p.Assert(t, DroppedEventsCounter, reasonLabel, queueFullReason)(xmetricstest.Value(1.0))
+}
+
+func push(q *partitionedQueue, key string, w WrpWithTime, priority rules.Priority) bool {
+	_, err := q.push(q.partitionFor(key), w, priority)
+	return err == nil
+}
+
+func TestParseShedEarly(t *testing.T) {
+	r, err := rules.NewRules([]rules.RuleConfig{
+		{
+			Regex:    ".*/online$",
+			Priority: "high",
+		},
+	})
+	require.Nil(t, err)
+
+	tests := []struct {
+		description   string
+		highWaterMark float64
+		queued        int
+		random        float64
+		destination   string
+		expectedErr   error
+	}{
+		{
+			description: "Disabled",
+			queued:      9,
+		},
+		{
+			description:   "Under High Water Mark",
+			highWaterMark: 0.5,
+			queued:        4,
+		},
+		{
+			description:   "Over High Water Mark Kept",
+			highWaterMark: 0.5,
+			queued:        8,
+			random:        0.7,
+		},
+		{
+			description:   "Over High Water Mark Shed",
+			highWaterMark: 0.5,
+			queued:        8,
+			random:        0.5,
+			expectedErr:   ErrQueueFull,
+		},
+		{
+			description:   "High Priority Not Shed",
+			highWaterMark: 0.5,
+			queued:        9,
+			destination:   "event/online",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			q, err := newPartitionedQueue(nil, 10, 0)
+			assert.Nil(err)
+			for i := 0; i < tc.queued; i++ {
+				assert.True(push(q, defaultPartition, WrpWithTime{}, rules.NormalPriority))
+			}
+			p := xmetricstest.NewProvider(nil, Metrics)
+			parser := RequestParser{
+				rc:       RecordConfig{rules: r},
+				config:   Config{HighWaterMark: tc.highWaterMark},
+				measures: NewMeasures(p),
+				queue:    q,
+				random:   func() float64 { return tc.random },
+			}
+			err = parser.Parse(WrpWithTime{Message: wrp.Message{Destination: tc.destination}})
+			assert.Equal(tc.expectedErr, err)
+			if tc.expectedErr != nil {
+				p.Assert(t, DroppedEventsCounter, reasonLabel, highWaterMarkReason)(xmetricstest.Value(1.0))
+			}
+		})
+	}
+}
+
+func TestParseShuttingDown(t *testing.T) {
+	assert := assert.New(t)
+	q, err := newPartitionedQueue(nil, 5, 0)
+	assert.Nil(err)
+	p := xmetricstest.NewProvider(nil, Metrics)
+	parser := RequestParser{
+		measures: NewMeasures(p),
+		queue:    q,
+	}
+	q.stop()
+	assert.Equal(ErrShuttingDown, parser.Parse(WrpWithTime{}))
+	p.Assert(t, DroppedEventsCounter, reasonLabel, queueFullReason)(xmetricstest.Value(0.0))
+}
+
+func TestDrainTime(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Now()
+	q, err := newPartitionedQueue(nil, 100, 0)
+	assert.Nil(err)
+	q.drained = newRateTracker(func() time.Time { return now })
+	parser := RequestParser{queue: q}
+
+	d, ok := parser.DrainTime()
+	assert.True(ok)
+	assert.Equal(time.Duration(0), d)
+
+	for i := 0; i < 30; i++ {
+		assert.True(push(q, defaultPartition, WrpWithTime{}, rules.NormalPriority))
+	}
+	_, ok = parser.DrainTime()
+	assert.False(ok)
+
+	for i := 0; i < 10; i++ {
+		_, _, ok = q.next()
+		assert.True(ok)
+	}
+	now = now.Add(time.Second)
+	d, ok = parser.DrainTime()
+	assert.True(ok)
+	assert.Equal(2*time.Second, d)
+}
@@ -0,0 +1,69 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package requestParser
+
+import (
+	"sync"
+	"time"
+)
+
+const rateWindow = time.Second
+
+// rateTracker counts events in fixed windows and reports the rate of the most
+// recently finished window.
+type rateTracker struct {
+	lock        sync.Mutex
+	now         func() time.Time
+	windowStart time.Time
+	count       int
+	rate        float64
+}
+
+func newRateTracker(now func() time.Time) *rateTracker {
+	return &rateTracker{
+		now:         now,
+		windowStart: now(),
+	}
+}
+
+func (t *rateTracker) mark() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.roll()
+	t.count++
+}
+
+// perSecond returns the rate of events per second.
+func (t *rateTracker) perSecond() float64 {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.roll()
+	return t.rate
+}
+
+// roll finishes the current window if it has passed.  The lock must be held.
+func (t *rateTracker) roll() {
+	now := t.now()
+	elapsed := now.Sub(t.windowStart)
+	if elapsed < rateWindow {
+		return
+	}
+	t.rate = float64(t.count) / elapsed.Seconds()
+	t.count = 0
+	t.windowStart = now
+}
@@ -0,0 +1,44 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package requestParser
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateTracker(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Now()
+	tracker := newRateTracker(func() time.Time { return now })
+
+	for i := 0; i < 10; i++ {
+		tracker.mark()
+	}
+	// the window hasn't finished yet.
+	assert.Equal(0.0, tracker.perSecond())
+
+	now = now.Add(2 * time.Second)
+	assert.Equal(5.0, tracker.perSecond())
+
+	// nothing was marked during the last window.
+	now = now.Add(time.Second)
+	assert.Equal(0.0, tracker.perSecond())
+}
@@ -19,6 +19,7 @@ package requestParser
 
 import (
 	"errors"
+	"math/rand"
 	"regexp"
 	"sync"
 	"time"
@@ -44,8 +45,17 @@ var (
 	errUnexpectedWRPType = errors.New("unexpected wrp message type")
 	errFutureBirthdate   = errors.New("birthdate is too far in the future")
 	errExpired           = errors.New("deathdate has passed")
-	errBlacklist         = errors.New("device is in blacklist")
-	errQueueFull         = errors.New("queue full")
+
+	// ErrBlacklisted is given to an event's Done when its device is in the
+	// blacklist.
+	ErrBlacklisted = errors.New("device is in blacklist")
+	// ErrInsertFailed is given to an event's Done when its record couldn't
+	// be inserted.
+	ErrInsertFailed = errors.New("failed to insert record")
+	// ErrQueueFull is returned by Parse when there isn't room for the event.
+	ErrQueueFull = errors.New("queue full")
+	// ErrShuttingDown is returned by Parse once the RequestParser is stopping.
+	ErrShuttingDown = errors.New("request parser is shutting down")
 
 	defaultLogger = log.NewNopLogger()
 )
@@ -64,6 +74,32 @@ type inserter interface {
 	Insert(record batchInserter.RecordWithTime) error
 }
 
+// PriorityInserter is an inserter that can insert records with a higher
+// priority ahead of others.  If the inserter given to the RequestParser
+// implements it, each record is inserted with the priority of its rule.
+type PriorityInserter interface {
+	InsertWithPriority(record batchInserter.RecordWithTime, priority rules.Priority) error
+}
+
+// TargetInserter is an inserter that can store records somewhere other than
+// the database.  If the inserter given to the RequestParser implements it,
+// each record is inserted into the target named by its rule, with the rule's
+// priority.
+type TargetInserter interface {
+	InsertToTarget(record batchInserter.RecordWithTime, target string, priority rules.Priority) error
+}
+
+// TrackingInserter is an inserter that can tell when a record has been
+// written, since inserting a record only queues it to be written in a batch.
+// If the inserter given to the RequestParser implements it, an event's Done
+// is called once its record has been written instead of once it's queued.
+type TrackingInserter interface {
+	// Track calls done with the result of writing the record's batch.  It
+	// must be called before the record is inserted.  If inserting the record
+	// fails, untrack is called instead.
+	Track(record db.Record, done func(error)) (untrack func())
+}
+
 type Config struct {
 	MetadataMaxSize int
 	PayloadMaxSize  int
@@ -71,6 +107,16 @@ type Config struct {
 	MaxWorkers      int
 	DefaultTTL      time.Duration
 	RegexRules      []rules.RuleConfig
+	PartnerPolicies []rules.PartnerPolicyConfig
+	PartitionBy     string
+	Partitions      []PartitionConfig
+	HighWaterMark   float64
+
+	// MaxPartitions is how many partitions are created for keys that don't
+	// have one configured.  Once there are that many, the other keys share
+	// the default partition.  Defaults to 100; below 0, no partitions are
+	// created.
+	MaxPartitions int
 }
 
 type RecordConfig struct {
@@ -78,6 +124,7 @@ type RecordConfig struct {
 	blacklist   blacklist.List
 	currTime    func() time.Time
 	rules       rules.Rules
+	policies    rules.PartnerPolicies
 	timeTracker TimeTracker
 	encrypter   voynicrypto.Encrypt
 }
@@ -90,12 +137,30 @@ type RequestParser struct {
 	wg               sync.WaitGroup
 	measures         *Measures
 	eventTypeMetrics EventTypeMetrics
-	requestQueue     chan WrpWithTime
+	queue            *partitionedQueue
+	random           func() float64
 }
 
 type WrpWithTime struct {
 	Message   wrp.Message
 	Beginning time.Time
+
+	// Rules replaces the parser's rules for this event, if set.
+	Rules rules.Rules
+
+	// Done is called, if set, once the event's record has been inserted, or
+	// once the event is dropped, with the reason it was dropped:
+	// ErrBlacklisted if its device is in the blacklist, ErrQueueFull if it
+	// was shed for a higher priority event, ErrInsertFailed if its record
+	// couldn't be inserted, or else the error creating its record.  If Parse
+	// returns an error, Done isn't called.
+	Done func(error)
+}
+
+func (w WrpWithTime) finish(err error) {
+	if w.Done != nil {
+		w.Done(err)
+	}
 }
 
 func NewRequestParser(config Config, logger log.Logger, metricsRegistry provider.Provider, inserter inserter, blacklist blacklist.List, encrypter voynicrypto.Encrypt, timeTracker TimeTracker) (*RequestParser, error) {
@@ -108,6 +173,10 @@ func NewRequestParser(config Config, logger log.Logger, metricsRegistry provider
 	if inserter == nil {
 		return nil, errors.New("no inserter")
 	}
+	policies, err := rules.NewPartnerPolicies(config.PartnerPolicies)
+	if err != nil {
+		return nil, emperror.Wrap(err, "failed to create partner policies from config")
+	}
 	rules, err := rules.NewRules(config.RegexRules)
 	if err != nil {
 		return nil, emperror.Wrap(err, "failed to create rules from config")
@@ -129,6 +198,12 @@ func NewRequestParser(config Config, logger log.Logger, metricsRegistry provider
 	if config.QueueSize < defaultMinQueueSize {
 		config.QueueSize = defaultMinQueueSize
 	}
+	if err := validPartitionBy(config.PartitionBy); err != nil {
+		return nil, err
+	}
+	if config.HighWaterMark <= 0 || config.HighWaterMark >= 1 {
+		config.HighWaterMark = 0
+	}
 	if logger == nil {
 		logger = defaultLogger
 	}
@@ -137,12 +212,23 @@ func NewRequestParser(config Config, logger log.Logger, metricsRegistry provider
 	if metricsRegistry != nil {
 		measures = NewMeasures(metricsRegistry)
 	}
-	queue := make(chan WrpWithTime, config.QueueSize)
+	maxPartitions := config.MaxPartitions
+	switch {
+	case config.PartitionBy == PartitionByNone || maxPartitions < 0:
+		maxPartitions = 0
+	case maxPartitions == 0:
+		maxPartitions = defaultMaxPartitions
+	}
+	queue, err := newPartitionedQueue(config.Partitions, config.QueueSize, maxPartitions)
+	if err != nil {
+		return nil, emperror.Wrap(err, "failed to create partitioned queue from config")
+	}
 	workers := semaphore.New(config.MaxWorkers)
 	template, typeIndex := createEventTemplateRegex(eventRegexTemplate, logger)
 	recordConfig := RecordConfig{
 		inserter:    inserter,
 		rules:       rules,
+		policies:    policies,
 		blacklist:   blacklist,
 		encrypter:   encrypter,
 		currTime:    time.Now,
@@ -155,7 +241,8 @@ func NewRequestParser(config Config, logger log.Logger, metricsRegistry provider
 		logger:           logger,
 		measures:         measures,
 		parseWorkers:     workers,
-		requestQueue:     queue,
+		queue:            queue,
+		random:           rand.Float64,
 		eventTypeMetrics: EventTypeMetrics{Regex: template, EventTypeIndex: typeIndex},
 	}
 
@@ -167,31 +254,107 @@ func (r *RequestParser) Start() {
 	go r.parseRequests()
 }
 
-func (r *RequestParser) Parse(wrpWithTime WrpWithTime) (err error) {
-	select {
-	case r.requestQueue <- wrpWithTime:
-		if r.measures != nil {
-			r.measures.ParsingQueue.Add(1.0)
+func (r *RequestParser) Parse(wrpWithTime WrpWithTime) error {
+	rule, _ := r.rulesFor(wrpWithTime).FindRule(wrpWithTime.Message.Destination)
+	priority := rulePriority(rule)
+	p := r.queue.partitionFor(r.partitionKey(wrpWithTime.Message, rule))
+	if r.shedEarly(p, priority) {
+		r.countQueueDrop(p, highWaterMarkReason)
+		return ErrQueueFull
+	}
+	shed, err := r.queue.push(p, wrpWithTime, priority)
+	if err != nil {
+		if err == ErrQueueFull {
+			r.countQueueDrop(p, queueFullReason)
 		}
-	default:
-		if r.measures != nil {
-			r.measures.DroppedEventsCount.With(reasonLabel, queueFullReason).Add(1.0)
+		return err
+	}
+	if shed != nil {
+		r.countQueueDrop(p, shedReason)
+		if r.rc.timeTracker != nil {
+			r.rc.timeTracker.TrackTime(time.Since(shed.Beginning))
 		}
-		err = errQueueFull
+		shed.finish(ErrQueueFull)
+		return nil
+	}
+	if r.measures != nil {
+		r.measures.ParsingQueue.Add(1.0)
+		r.measures.PartitionQueue.With(partitionLabel, p.name).Add(1.0)
+	}
+	return nil
+}
+
+// shedEarly decides whether to drop an event before its partition is full.
+// Once the partition is past the high water mark, the chance of dropping an
+// event grows from nothing to certain as the partition fills up, which
+// spreads out the retries of the senders.  High priority events are never
+// shed early.
+func (r *RequestParser) shedEarly(p *partition, priority rules.Priority) bool {
+	if r.config.HighWaterMark <= 0 || priority == rules.HighPriority {
+		return false
+	}
+	fill := r.queue.fill(p)
+	if fill < r.config.HighWaterMark {
+		return false
+	}
+	chance := (fill - r.config.HighWaterMark) / (1 - r.config.HighWaterMark)
+	return r.random() < chance
+}
+
+// DrainTime estimates how long it will take to empty the parsing queue at the
+// rate events have recently been taken off of it.  If nothing has been taken
+// off the queue recently, the estimate can't be made and false is returned.
+func (r *RequestParser) DrainTime() (time.Duration, bool) {
+	depth, rate := r.queue.depth(), r.queue.drainRate()
+	if depth == 0 {
+		return 0, true
+	}
+	if rate <= 0 {
+		return 0, false
+	}
+	return time.Duration(float64(depth) / rate * float64(time.Second)), true
+}
+
+func (r *RequestParser) countQueueDrop(p *partition, reason string) {
+	if r.measures != nil {
+		r.measures.DroppedEventsCount.With(reasonLabel, reason).Add(1.0)
+		r.measures.PartitionDroppedEventsCount.With(partitionLabel, p.name).Add(1.0)
 	}
-	return
+}
+
+// ruleTarget gets the target of a record's rule, which is empty for records
+// stored in the database.
+func ruleTarget(rule *rules.Rule) string {
+	if rule == nil {
+		return ""
+	}
+	return rule.Target()
+}
+
+// rulesFor gets the rules for an event, which are the parser's rules unless
+// the event came with its own.
+func (r *RequestParser) rulesFor(request WrpWithTime) rules.Rules {
+	if request.Rules != nil {
+		return request.Rules
+	}
+	return r.rc.rules
 }
 
 func (r *RequestParser) Stop() {
-	close(r.requestQueue)
+	r.queue.stop()
 	r.wg.Wait()
 }
 
 func (r *RequestParser) parseRequests() {
 	defer r.wg.Done()
-	for request := range r.requestQueue {
+	for {
+		request, p, ok := r.queue.next()
+		if !ok {
+			break
+		}
 		if r.measures != nil {
 			r.measures.ParsingQueue.Add(-1.0)
+			r.measures.PartitionQueue.With(partitionLabel, p.name).Add(-1.0)
 		}
 		r.parseWorkers.Acquire()
 		go r.parseRequest(request)
@@ -213,7 +376,7 @@ func (r *RequestParser) parseRequest(request WrpWithTime) {
 
 	r.measures.EventsCount.With(partnerIDLabel, partnerID, eventDestLabel, eventDestination).Add(1.0)
 
-	rule, err := r.rc.rules.FindRule(request.Message.Destination)
+	rule, err := r.rulesFor(request).FindRule(request.Message.Destination)
 	if err != nil {
 		logging.Info(r.logger).Log(logging.MessageKey(), "Could not get rule", logging.ErrorKey(), err, "destination", request.Message.Destination)
 	}
@@ -233,11 +396,37 @@ func (r *RequestParser) recordHandler(request WrpWithTime, eventType db.EventTyp
 		return
 	}
 
-	err = r.rc.inserter.Insert(batchInserter.RecordWithTime{Record: record, Beginning: request.Beginning})
+	rwt := batchInserter.RecordWithTime{Record: record, Beginning: request.Beginning}
+	untrack := func() {}
+	tracker, tracked := r.rc.inserter.(TrackingInserter)
+	tracked = tracked && request.Done != nil
+	if tracked {
+		untrack = tracker.Track(record, func(err error) {
+			if err != nil {
+				request.finish(ErrInsertFailed)
+				return
+			}
+			request.finish(nil)
+		})
+	}
+	switch i := r.rc.inserter.(type) {
+	case TargetInserter:
+		err = i.InsertToTarget(rwt, ruleTarget(rule), rulePriority(rule))
+	case PriorityInserter:
+		err = i.InsertWithPriority(rwt, rulePriority(rule))
+	default:
+		err = r.rc.inserter.Insert(rwt)
+	}
 	if err != nil {
+		untrack()
 		r.measures.DroppedEventsCount.With(reasonLabel, insertFailReason).Add(1.0)
 		logging.Warn(r.logger, emperror.Context(err)...).Log(logging.MessageKey(),
 			"Failed to insert record", logging.ErrorKey(), err.Error())
+		request.finish(ErrInsertFailed)
+		return
+	}
+	if !tracked {
+		request.finish(nil)
 	}
 }
 
@@ -247,11 +436,13 @@ func (r *RequestParser) handleCreateRecordErr(request WrpWithTime, record db.Rec
 		logging.Info(r.logger, emperror.Context(err)...).Log(logging.MessageKey(),
 			"Failed to create record", logging.ErrorKey(), err.Error())
 		r.rc.timeTracker.TrackTime(time.Since(request.Beginning))
+		request.finish(ErrBlacklisted)
 		return
 	}
 	logging.Warn(r.logger, emperror.Context(err)...).Log(logging.MessageKey(),
 		"Failed to create record", logging.ErrorKey(), err.Error())
 	r.rc.timeTracker.TrackTime(time.Since(request.Beginning))
+	request.finish(err)
 }
 
 // create compiled regex for events regex template
@@ -27,7 +27,9 @@ import (
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/metrics/provider"
 
+	db "github.com/xmidt-org/codex-db"
 	"github.com/xmidt-org/codex-db/blacklist"
+	"github.com/xmidt-org/svalinn/rules"
 	"github.com/xmidt-org/wrp-go/v3"
 
 	"github.com/stretchr/testify/assert"
@@ -141,10 +143,12 @@ func TestNewRequestParser(t *testing.T) {
 			assert := assert.New(t)
 			rp, err := NewRequestParser(tc.config, tc.logger, tc.registry, tc.inserter, tc.blacklist, tc.encrypter, nil)
 			if rp != nil {
-				tc.expectedRequestParser.requestQueue = rp.requestQueue
+				tc.expectedRequestParser.queue = rp.queue
 				tc.expectedRequestParser.parseWorkers = rp.parseWorkers
 				tc.expectedRequestParser.eventTypeMetrics = rp.eventTypeMetrics
 				rp.rc.currTime = nil
+				assert.NotNil(rp.random)
+				rp.random = nil
 			}
 			assert.Equal(tc.expectedRequestParser, rp)
 			if tc.expectedErr == nil || err == nil {
@@ -175,6 +179,7 @@ func TestParseRequest(t *testing.T) {
 		blacklistCalled    bool
 		insertCalled       bool
 		timeExpected       bool
+		expectDoneErr      bool
 	}{
 		{
 			description:     "Success",
@@ -187,6 +192,7 @@ func TestParseRequest(t *testing.T) {
 		{
 			description:      "Empty ID Error",
 			expectParseCount: 1.0,
+			expectDoneErr:    true,
 		},
 		{
 			description:        "Encrypt Error",
@@ -196,6 +202,7 @@ func TestParseRequest(t *testing.T) {
 			encryptCalled:      true,
 			blacklistCalled:    true,
 			timeExpected:       true,
+			expectDoneErr:      true,
 		},
 		{
 			description:       "Insert Error",
@@ -206,6 +213,7 @@ func TestParseRequest(t *testing.T) {
 			insertCalled:      true,
 			blacklistCalled:   true,
 			timeExpected:      true,
+			expectDoneErr:     true,
 		},
 		{
 			description: "Event Metrics – Random Event",
@@ -292,8 +300,15 @@ func TestParseRequest(t *testing.T) {
 				eventTypeMetrics: EventTypeMetrics{Regex: eventRegex, EventTypeIndex: eventTypeIndex},
 			}
 
+			var doneErrs []error
+			done := func(err error) {
+				doneErrs = append(doneErrs, err)
+			}
 			handler.parseWorkers.Acquire()
-			handler.parseRequest(WrpWithTime{Message: tc.req, Beginning: beginTime})
+			handler.parseRequest(WrpWithTime{Message: tc.req, Beginning: beginTime, Done: done})
+			if testassert.Len(doneErrs, 1) {
+				testassert.Equal(tc.expectDoneErr, doneErrs[0] != nil)
+			}
 			mockInserter.AssertExpectations(t)
 			mblacklist.AssertExpectations(t)
 			encrypter.AssertExpectations(t)
@@ -308,6 +323,98 @@ func TestParseRequest(t *testing.T) {
 	}
 }
 
+func TestRecordHandlerDone(t *testing.T) {
+	errInsert := errors.New("insert failed")
+	goodTime, err := time.Parse(time.RFC3339Nano, "2019-02-13T21:19:02.614191735Z")
+	assert.Nil(t, err)
+	tests := []struct {
+		description     string
+		blacklisted     bool
+		tracking        bool
+		insertErr       error
+		batchErr        error
+		expectedDoneErr error
+	}{
+		{
+			description: "Queued",
+		},
+		{
+			description:     "Insert Error",
+			insertErr:       errInsert,
+			expectedDoneErr: ErrInsertFailed,
+		},
+		{
+			description:     "Blacklisted",
+			blacklisted:     true,
+			expectedDoneErr: ErrBlacklisted,
+		},
+		{
+			description: "Batch Written",
+			tracking:    true,
+		},
+		{
+			description:     "Batch Failed",
+			tracking:        true,
+			batchErr:        errInsert,
+			expectedDoneErr: ErrInsertFailed,
+		},
+		{
+			description:     "Tracked Insert Error",
+			tracking:        true,
+			insertErr:       errInsert,
+			expectedDoneErr: ErrInsertFailed,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			encrypter := new(mockEncrypter)
+			encrypter.On("EncryptMessage", mock.Anything).Return(nil)
+			mblacklist := new(mockBlacklist)
+			mblacklist.On("InList", mock.Anything).Return("testing", tc.blacklisted)
+			mockTimeTracker := new(mockTimeTracker)
+			mockTimeTracker.On("TrackTime", mock.Anything)
+			tracker := new(trackingInserter)
+			var inserter inserter = &tracker.mockInserter
+			if tc.tracking {
+				inserter = tracker
+			}
+			tracker.On("Insert", mock.Anything).Return(tc.insertErr)
+
+			handler := RequestParser{
+				rc: RecordConfig{
+					encrypter:   encrypter,
+					inserter:    inserter,
+					timeTracker: mockTimeTracker,
+					blacklist:   mblacklist,
+					currTime:    func() time.Time { return goodTime },
+				},
+				config: Config{
+					PayloadMaxSize:  9999,
+					MetadataMaxSize: 9999,
+					DefaultTTL:      time.Second,
+				},
+				measures: NewMeasures(xmetricstest.NewProvider(nil, Metrics)),
+				logger:   logging.NewTestLogger(nil, t),
+			}
+
+			var doneErrs []error
+			done := func(err error) {
+				doneErrs = append(doneErrs, err)
+			}
+			handler.recordHandler(WrpWithTime{Message: goodEvent, Beginning: time.Now(), Done: done}, db.Default, nil)
+			if tc.tracking && tc.insertErr == nil {
+				// the event isn't done until its record's batch is written.
+				assert.Empty(doneErrs)
+				tracker.done(tc.batchErr)
+			}
+			assert.Equal([]error{tc.expectedDoneErr}, doneErrs)
+			assert.Equal(tc.tracking && tc.insertErr != nil, tracker.untracked)
+		})
+	}
+}
+
 func TestCreateEventTemplateRegex(t *testing.T) {
 	tests := []struct {
 		description   string
@@ -424,3 +531,99 @@ func TestGetEventDestinationType(t *testing.T) {
 		})
 	}
 }
+
+func TestRecordHandlerPriority(t *testing.T) {
+	assert := assert.New(t)
+	goodTime, err := time.Parse(time.RFC3339Nano, "2019-02-13T21:19:02.614191735Z")
+	assert.Nil(err)
+	r, err := rules.NewRules([]rules.RuleConfig{
+		{
+			Regex:    ".*",
+			Priority: "high",
+		},
+	})
+	assert.Nil(err)
+	rule, err := r.FindRule(goodEvent.Destination)
+	assert.Nil(err)
+
+	encrypter := new(mockEncrypter)
+	encrypter.On("EncryptMessage", mock.Anything).Return(nil).Once()
+	mblacklist := new(mockBlacklist)
+	mblacklist.On("InList", mock.Anything).Return("", false).Once()
+	inserter := new(mockPriorityInserter)
+	inserter.On("InsertWithPriority", mock.Anything, rules.HighPriority).Return(nil).Once()
+
+	handler := RequestParser{
+		rc: RecordConfig{
+			encrypter: encrypter,
+			inserter:  inserter,
+			blacklist: mblacklist,
+			currTime:  func() time.Time { return goodTime },
+		},
+		config: Config{
+			DefaultTTL: time.Second,
+		},
+		measures: NewMeasures(xmetricstest.NewProvider(nil, Metrics)),
+		logger:   logging.NewTestLogger(nil, t),
+	}
+	handler.recordHandler(WrpWithTime{Message: goodEvent, Beginning: time.Now()}, db.Default, rule)
+	inserter.AssertExpectations(t)
+}
+
+func TestRecordHandlerTarget(t *testing.T) {
+	assert := assert.New(t)
+	goodTime, err := time.Parse(time.RFC3339Nano, "2019-02-13T21:19:02.614191735Z")
+	assert.Nil(err)
+	r, err := rules.NewRules([]rules.RuleConfig{
+		{
+			Regex:    ".*",
+			Priority: "low",
+			Target:   "telemetry",
+		},
+	})
+	assert.Nil(err)
+	rule, err := r.FindRule(goodEvent.Destination)
+	assert.Nil(err)
+
+	encrypter := new(mockEncrypter)
+	encrypter.On("EncryptMessage", mock.Anything).Return(nil).Twice()
+	mblacklist := new(mockBlacklist)
+	mblacklist.On("InList", mock.Anything).Return("", false).Twice()
+	inserter := new(mockTargetInserter)
+	inserter.On("InsertToTarget", mock.Anything, "telemetry", rules.LowPriority).Return(nil).Once()
+	inserter.On("InsertToTarget", mock.Anything, "", rules.NormalPriority).Return(nil).Once()
+
+	handler := RequestParser{
+		rc: RecordConfig{
+			encrypter: encrypter,
+			inserter:  inserter,
+			blacklist: mblacklist,
+			currTime:  func() time.Time { return goodTime },
+		},
+		config: Config{
+			DefaultTTL: time.Second,
+		},
+		measures: NewMeasures(xmetricstest.NewProvider(nil, Metrics)),
+		logger:   logging.NewTestLogger(nil, t),
+	}
+	handler.recordHandler(WrpWithTime{Message: goodEvent, Beginning: time.Now()}, db.Default, rule)
+	handler.recordHandler(WrpWithTime{Message: goodEvent, Beginning: time.Now()}, db.Default, nil)
+	inserter.AssertExpectations(t)
+}
+
+func TestRulesFor(t *testing.T) {
+	assert := assert.New(t)
+	parserRules, err := rules.NewRules([]rules.RuleConfig{{Regex: ".*", EventType: "parser"}})
+	assert.Nil(err)
+	eventRules, err := rules.NewRules([]rules.RuleConfig{{Regex: ".*", EventType: "event"}})
+	assert.Nil(err)
+	handler := RequestParser{rc: RecordConfig{rules: parserRules}}
+
+	rule, err := handler.rulesFor(WrpWithTime{Message: goodEvent}).FindRule(goodEvent.Destination)
+	assert.Nil(err)
+	assert.Equal("parser", rule.EventType())
+
+	rule, err = handler.rulesFor(WrpWithTime{Message: goodEvent, Rules: eventRules}).FindRule(goodEvent.Destination)
+	assert.Nil(err)
+	assert.Equal("event", rule.EventType())
+}
@@ -0,0 +1,118 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package rules
+
+import (
+	"errors"
+	"time"
+
+	"github.com/goph/emperror"
+)
+
+var (
+	errEmptyPartnerID     = errors.New("partner policy has an empty partner id")
+	errDuplicatePartnerID = errors.New("partner policy already exists for this partner id")
+	errMaxTTLTooSmall     = errors.New("partner policy max ttl is smaller than its default ttl")
+)
+
+// PartnerPolicyConfig describes the retention and storage limits for the
+// events of a single partner.  Zero values mean the partner has no limit of
+// its own and the rule or global configuration is used.
+type PartnerPolicyConfig struct {
+	PartnerID       string
+	DefaultTTL      time.Duration
+	MaxTTL          time.Duration
+	DisablePayload  bool
+	MetadataMaxSize int
+}
+
+type PartnerPolicy struct {
+	partnerID       string
+	defaultTTL      time.Duration
+	maxTTL          time.Duration
+	disablePayload  bool
+	metadataMaxSize int
+}
+
+type PartnerPolicies map[string]*PartnerPolicy
+
+func NewPartnerPolicies(policies []PartnerPolicyConfig) (PartnerPolicies, error) {
+	if len(policies) == 0 {
+		return nil, nil
+	}
+	parsedPolicies := make(PartnerPolicies, len(policies))
+	for _, p := range policies {
+		if p.PartnerID == "" {
+			return nil, errEmptyPartnerID
+		}
+		if _, ok := parsedPolicies[p.PartnerID]; ok {
+			return nil, emperror.With(errDuplicatePartnerID, "partner id", p.PartnerID)
+		}
+		if p.MaxTTL > 0 && p.DefaultTTL > p.MaxTTL {
+			return nil, emperror.With(errMaxTTLTooSmall, "partner id", p.PartnerID, "default ttl", p.DefaultTTL, "max ttl", p.MaxTTL)
+		}
+		parsedPolicies[p.PartnerID] = &PartnerPolicy{
+			partnerID:       p.PartnerID,
+			defaultTTL:      p.DefaultTTL,
+			maxTTL:          p.MaxTTL,
+			disablePayload:  p.DisablePayload,
+			metadataMaxSize: p.MetadataMaxSize,
+		}
+	}
+	return parsedPolicies, nil
+}
+
+// FindPolicy returns the policy of the first partner id that has one, or nil
+// if none of the partner ids have a policy.
+func (p PartnerPolicies) FindPolicy(partnerIDs []string) *PartnerPolicy {
+	for _, id := range partnerIDs {
+		if policy, ok := p[id]; ok {
+			return policy
+		}
+	}
+	return nil
+}
+
+func (p *PartnerPolicy) PartnerID() string {
+	return p.partnerID
+}
+
+// TTL determines the ttl for a record given the ttl chosen by a rule and the
+// global default.  A rule's ttl wins over the partner's default, which wins
+// over the global default, but none of them can exceed the partner's max.
+func (p *PartnerPolicy) TTL(ruleTTL time.Duration, defaultTTL time.Duration) time.Duration {
+	ttl := ruleTTL
+	if ttl == 0 {
+		ttl = p.defaultTTL
+	}
+	if ttl == 0 {
+		ttl = defaultTTL
+	}
+	if p.maxTTL > 0 && ttl > p.maxTTL {
+		ttl = p.maxTTL
+	}
+	return ttl
+}
+
+func (p *PartnerPolicy) PayloadAllowed() bool {
+	return !p.disablePayload
+}
+
+func (p *PartnerPolicy) MetadataMaxSize() int {
+	return p.metadataMaxSize
+}
@@ -0,0 +1,166 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package rules
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPartnerPolicies(t *testing.T) {
+	tests := []struct {
+		description    string
+		policies       []PartnerPolicyConfig
+		expectedOutput PartnerPolicies
+		expectedErr    error
+	}{
+		{
+			description: "No Policies Success",
+		},
+		{
+			description: "Success With Policies",
+			policies: []PartnerPolicyConfig{
+				{
+					PartnerID:       "comcast",
+					DefaultTTL:      time.Hour,
+					MaxTTL:          2 * time.Hour,
+					DisablePayload:  true,
+					MetadataMaxSize: 10,
+				},
+			},
+			expectedOutput: PartnerPolicies{
+				"comcast": &PartnerPolicy{
+					partnerID:       "comcast",
+					defaultTTL:      time.Hour,
+					maxTTL:          2 * time.Hour,
+					disablePayload:  true,
+					metadataMaxSize: 10,
+				},
+			},
+		},
+		{
+			description: "Empty Partner ID Error",
+			policies:    []PartnerPolicyConfig{{DefaultTTL: time.Hour}},
+			expectedErr: errEmptyPartnerID,
+		},
+		{
+			description: "Duplicate Partner ID Error",
+			policies:    []PartnerPolicyConfig{{PartnerID: "a"}, {PartnerID: "a"}},
+			expectedErr: errDuplicatePartnerID,
+		},
+		{
+			description: "Max TTL Too Small Error",
+			policies: []PartnerPolicyConfig{
+				{
+					PartnerID:  "a",
+					DefaultTTL: time.Hour,
+					MaxTTL:     time.Minute,
+				},
+			},
+			expectedErr: errMaxTTLTooSmall,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			policies, err := NewPartnerPolicies(tc.policies)
+			if tc.expectedErr == nil || err == nil {
+				assert.Equal(tc.expectedErr, err)
+			} else {
+				assert.Contains(err.Error(), tc.expectedErr.Error())
+			}
+			assert.Equal(tc.expectedOutput, policies)
+		})
+	}
+}
+
+func TestFindPolicy(t *testing.T) {
+	goodPolicy := &PartnerPolicy{partnerID: "comcast"}
+	policies := PartnerPolicies{"comcast": goodPolicy}
+	tests := []struct {
+		description    string
+		policies       PartnerPolicies
+		partnerIDs     []string
+		expectedPolicy *PartnerPolicy
+	}{
+		{
+			description:    "Success",
+			policies:       policies,
+			partnerIDs:     []string{"other", "comcast"},
+			expectedPolicy: goodPolicy,
+		},
+		{
+			description: "No Match",
+			policies:    policies,
+			partnerIDs:  []string{"other"},
+		},
+		{
+			description: "No Policies",
+			partnerIDs:  []string{"comcast"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			assert.Equal(tc.expectedPolicy, tc.policies.FindPolicy(tc.partnerIDs))
+		})
+	}
+}
+
+func TestPartnerPolicyTTL(t *testing.T) {
+	tests := []struct {
+		description string
+		policy      PartnerPolicy
+		ruleTTL     time.Duration
+		expectedTTL time.Duration
+	}{
+		{
+			description: "Global Default",
+			policy:      PartnerPolicy{},
+			expectedTTL: time.Minute,
+		},
+		{
+			description: "Partner Default",
+			policy:      PartnerPolicy{defaultTTL: time.Hour},
+			expectedTTL: time.Hour,
+		},
+		{
+			description: "Rule Over Partner Default",
+			policy:      PartnerPolicy{defaultTTL: time.Hour},
+			ruleTTL:     2 * time.Hour,
+			expectedTTL: 2 * time.Hour,
+		},
+		{
+			description: "Capped By Max",
+			policy:      PartnerPolicy{maxTTL: 30 * time.Second},
+			ruleTTL:     2 * time.Hour,
+			expectedTTL: 30 * time.Second,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			assert.Equal(tc.expectedTTL, tc.policy.TTL(tc.ruleTTL, time.Minute))
+		})
+	}
+}
@@ -20,20 +20,61 @@ package rules
 import (
 	"errors"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/goph/emperror"
 )
 
 var (
-	errNoMatch = errors.New("No key matches this destination")
+	errNoMatch         = errors.New("No key matches this destination")
+	errInvalidPriority = errors.New("invalid priority")
 )
 
+// Priority determines the order events are serviced in and which events are
+// shed first when svalinn is overloaded.
+type Priority int
+
+const (
+	LowPriority Priority = iota
+	NormalPriority
+	HighPriority
+)
+
+func (p Priority) String() string {
+	switch p {
+	case LowPriority:
+		return "low"
+	case HighPriority:
+		return "high"
+	}
+	return "normal"
+}
+
+// ParsePriority parses the priority from a case-insensitive string.  An empty
+// string is NormalPriority.
+func ParsePriority(p string) (Priority, error) {
+	switch strings.ToLower(p) {
+	case "low":
+		return LowPriority, nil
+	case "", "normal":
+		return NormalPriority, nil
+	case "high":
+		return HighPriority, nil
+	}
+	return NormalPriority, emperror.With(errInvalidPriority, "priority", p)
+}
+
 type RuleConfig struct {
 	Regex        string
 	StorePayload bool
 	RuleTTL      time.Duration
 	EventType    string
+	Priority     string
+
+	// Target names where the rule's records are stored.  If empty, they're
+	// stored in the database.
+	Target string
 }
 
 type Rule struct {
@@ -41,6 +82,8 @@ type Rule struct {
 	storePayload bool
 	ttl          time.Duration
 	eventType    string
+	priority     Priority
+	target       string
 }
 
 type Rules []*Rule
@@ -55,7 +98,11 @@ func NewRules(rules []RuleConfig) (Rules, error) {
 		if err != nil {
 			return nil, emperror.WrapWith(err, "Failed to compile regexp rule", "regexp attempted", r.Regex)
 		}
-		parsedRules[i] = &Rule{regex, r.StorePayload, r.RuleTTL, r.EventType}
+		priority, err := ParsePriority(r.Priority)
+		if err != nil {
+			return nil, emperror.Wrap(err, "Failed to parse rule priority")
+		}
+		parsedRules[i] = &Rule{regex, r.StorePayload, r.RuleTTL, r.EventType, priority, r.Target}
 	}
 	return parsedRules, nil
 }
@@ -80,3 +127,11 @@ func (r *Rule) StorePayload() bool {
 func (r *Rule) TTL() time.Duration {
 	return r.ttl
 }
+
+func (r *Rule) Priority() Priority {
+	return r.priority
+}
+
+func (r *Rule) Target() string {
+	return r.target
+}
@@ -20,6 +20,7 @@ package rules
 import (
 	"errors"
 	"regexp"
+	"strings"
 	"testing"
 	"time"
 
@@ -45,6 +46,11 @@ func TestNewRules(t *testing.T) {
 					RuleTTL:      time.Duration(5) * time.Second,
 					EventType:    "test event",
 				},
+				{
+					Regex:    "online$",
+					Priority: "High",
+					Target:   "state",
+				},
 			},
 			expectedOutput: []*Rule{
 				&Rule{
@@ -52,10 +58,26 @@ func TestNewRules(t *testing.T) {
 					storePayload: true,
 					ttl:          time.Duration(5) * time.Second,
 					eventType:    "test event",
+					priority:     NormalPriority,
+				},
+				&Rule{
+					regex:    regexp.MustCompile("online$"),
+					priority: HighPriority,
+					target:   "state",
 				},
 			},
 			expectedErr: nil,
 		},
+		{
+			description: "Priority Error",
+			rules: []RuleConfig{
+				{
+					Regex:    ".*",
+					Priority: "urgent",
+				},
+			},
+			expectedErr: errInvalidPriority,
+		},
 		{
 			description: "Parse Error",
 			rules: []RuleConfig{
@@ -87,6 +109,7 @@ func TestFindRule(t *testing.T) {
 		storePayload: false,
 		ttl:          time.Duration(3) * time.Minute,
 		eventType:    "test event",
+		priority:     LowPriority,
 	}
 	tests := []struct {
 		description  string
@@ -123,6 +146,37 @@ func TestFindRule(t *testing.T) {
 				assert.Equal(tc.expectedRule.eventType, rule.EventType())
 				assert.Equal(tc.expectedRule.storePayload, rule.StorePayload())
 				assert.Equal(tc.expectedRule.ttl, rule.TTL())
+				assert.Equal(tc.expectedRule.priority, rule.Priority())
+			}
+		})
+	}
+}
+
+func TestParsePriority(t *testing.T) {
+	tests := []struct {
+		priority         string
+		expectedPriority Priority
+		expectedErr      error
+	}{
+		{priority: "", expectedPriority: NormalPriority},
+		{priority: "normal", expectedPriority: NormalPriority},
+		{priority: "LOW", expectedPriority: LowPriority},
+		{priority: "high", expectedPriority: HighPriority},
+		{priority: "urgent", expectedPriority: NormalPriority, expectedErr: errInvalidPriority},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.priority, func(t *testing.T) {
+			assert := assert.New(t)
+			p, err := ParsePriority(tc.priority)
+			assert.Equal(tc.expectedPriority, p)
+			if tc.expectedErr == nil || err == nil {
+				assert.Equal(tc.expectedErr, err)
+			} else {
+				assert.Contains(err.Error(), tc.expectedErr.Error())
+			}
+			if err == nil && tc.priority != "" {
+				assert.Equal(strings.ToLower(tc.priority), p.String())
 			}
 		})
 	}
@@ -0,0 +1,241 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1" // nolint:gosec // sha1 is still used by older webhook senders
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/goph/emperror"
+	"github.com/xmidt-org/bascule"
+	"github.com/xmidt-org/bascule/basculehttp"
+)
+
+const (
+	defaultSignatureAlgorithm = "sha1"
+)
+
+var (
+	errUnsupportedAlgorithm = errors.New("unsupported signature algorithm")
+	errEmptySecret          = errors.New("empty webhook secret")
+	errNoSecretExpiry       = errors.New("secret.rotation.previousSecretExpires is required with a previous secret")
+	errInvalidSecretExpiry  = errors.New("secret.rotation.previousSecretExpires isn't an RFC 3339 time")
+)
+
+// hashAlgorithm is an HMAC hash that can be used to sign the events sent to
+// Svalinn.  key is what comes before the delimiter in the signature header.
+type hashAlgorithm struct {
+	key     bascule.Authorization
+	newFunc func() hash.Hash
+}
+
+var hashAlgorithms = map[string]hashAlgorithm{
+	"sha1":   {key: "Sha1", newFunc: sha1.New},
+	"sha256": {key: "Sha256", newFunc: sha256.New},
+	"sha512": {key: "Sha512", newFunc: sha512.New},
+}
+
+// getHashAlgorithm finds the hash algorithm by name, ignoring case and
+// dashes.  An empty name gives the default, SHA-1.
+func getHashAlgorithm(name string) (hashAlgorithm, error) {
+	normalized := strings.ToLower(strings.Replace(name, "-", "", -1))
+	if normalized == "" {
+		normalized = defaultSignatureAlgorithm
+	}
+	alg, ok := hashAlgorithms[normalized]
+	if !ok {
+		return hashAlgorithm{}, emperror.With(errUnsupportedAlgorithm, "algorithm", name)
+	}
+	return alg, nil
+}
+
+// signatureHeader describes the header a signed request must have, such as
+// "X-Webpa-Signature: Sha1=<hex hmac>".  The algorithm isn't part of the
+// webhook registration, so the sender has to be set up to sign this way.
+func signatureHeader(config SecretConfig) (string, error) {
+	alg, err := getHashAlgorithm(config.Algorithm)
+	if err != nil {
+		return "", err
+	}
+	header, delimiter := config.Header, config.Delimiter
+	if header == "" {
+		header = basculehttp.DefaultHeaderName
+	}
+	if delimiter == "" {
+		delimiter = basculehttp.DefaultHeaderDelimiter
+	}
+	return header + ": " + string(alg.key) + delimiter + "<hex hmac>", nil
+}
+
+// SecretRotationConfig lets Svalinn accept signatures made with the previous
+// webhook secret while senders switch to the new one.  Each registration is
+// rotated on its own, so a previous secret is only accepted on the endpoint of
+// the registration it belonged to.  The previous secret is accepted until
+// PreviousSecretExpires, an RFC 3339 time, so restarting Svalinn can't make
+// the rotation last longer.
+type SecretRotationConfig struct {
+	PreviousSecret        string
+	PreviousSecretExpires string
+}
+
+// expires gets when the previous secret stops being accepted.
+func (c SecretRotationConfig) expires() (time.Time, error) {
+	if c.PreviousSecret == "" {
+		return time.Time{}, nil
+	}
+	if c.PreviousSecretExpires == "" {
+		return time.Time{}, errNoSecretExpiry
+	}
+	expires, err := time.Parse(time.RFC3339, c.PreviousSecretExpires)
+	if err != nil {
+		return time.Time{}, emperror.WrapWith(errInvalidSecretExpiry, err.Error(), "previousSecretExpires", c.PreviousSecretExpires)
+	}
+	return expires, nil
+}
+
+// codeError is an error that also gives the status code that should be used
+// in the response.
+type codeError struct {
+	code int
+	err  error
+}
+
+func (c codeError) Error() string {
+	return c.err.Error()
+}
+
+func (c codeError) StatusCode() int {
+	return c.code
+}
+
+// signatureFactory validates that the signature of a request matches the
+// HMAC of the body made with the current secret or, until it expires, the
+// previous secret.
+type signatureFactory struct {
+	alg             hashAlgorithm
+	current         string
+	previous        string
+	previousExpires time.Time
+	now             func() time.Time
+	validations     metrics.Counter
+	replay          *replayGuard
+}
+
+func (s *signatureFactory) ParseAndValidate(_ context.Context, req *http.Request, _ bascule.Authorization, value string) (bascule.Token, error) {
+	if req.Body == nil {
+		return nil, codeError{http.StatusBadRequest, errors.New("empty request body")}
+	}
+
+	msgBytes, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, codeError{http.StatusBadRequest, emperror.Wrap(err, "could not read request body")}
+	}
+	// restore the body for the handler.
+	req.Body = ioutil.NopCloser(bytes.NewBuffer(msgBytes))
+
+	signature, err := hex.DecodeString(value)
+	if err != nil {
+		return nil, codeError{http.StatusBadRequest, emperror.Wrap(err, "could not decode signature")}
+	}
+
+	var headers signedHeaders
+	if s.replay != nil {
+		headers, err = s.replay.signedHeaders(req)
+		if err != nil {
+			return nil, codeError{http.StatusForbidden, err}
+		}
+	}
+
+	secret := currentSecret
+	if !s.matches(s.current, headers.prefix, msgBytes, signature) {
+		if s.previous == "" || !s.now().Before(s.previousExpires) || !s.matches(s.previous, headers.prefix, msgBytes, signature) {
+			return nil, codeError{http.StatusForbidden, errors.New("invalid signature")}
+		}
+		secret = previousSecret
+	}
+	// only remember the nonces of valid requests, so they can't be used up by
+	// anyone without the secret.
+	if s.replay != nil {
+		if err := s.replay.useNonce(headers.nonce, headers.timestamp); err != nil {
+			return nil, codeError{http.StatusForbidden, err}
+		}
+	}
+	s.validations.With(secretLabel, secret).Add(1.0)
+	return bascule.NewToken(string(s.alg.key), value, bascule.NewAttributes(map[string]interface{}{secretLabel: secret})), nil
+}
+
+func (s *signatureFactory) matches(secret string, prefix []byte, body []byte, signature []byte) bool {
+	h := hmac.New(s.alg.newFunc, []byte(secret))
+	h.Write(prefix)
+	h.Write(body)
+	return hmac.Equal(h.Sum(nil), signature)
+}
+
+// newSignatureConstructor creates the middleware that validates the signature
+// of each request.  The current secret should be the one used when
+// registering, so the sender and Svalinn always hash with the same secret, and
+// the rotation should be the same registration's.
+func newSignatureConstructor(config SecretConfig, current string, rotation SecretRotationConfig, measures *Measures, options ...basculehttp.COption) (func(http.Handler) http.Handler, error) {
+	factory, err := newSignatureFactory(config, current, rotation, measures)
+	if err != nil {
+		return nil, err
+	}
+	alg := factory.alg
+	options = append(options,
+		basculehttp.WithTokenFactory(alg.key, factory),
+		basculehttp.WithHeaderName(config.Header),
+		basculehttp.WithHeaderDelimiter(config.Delimiter),
+	)
+	return basculehttp.NewConstructor(options...), nil
+}
+
+func newSignatureFactory(config SecretConfig, current string, rotation SecretRotationConfig, measures *Measures) (*signatureFactory, error) {
+	alg, err := getHashAlgorithm(config.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	if current == "" {
+		return nil, errEmptySecret
+	}
+	expires, err := rotation.expires()
+	if err != nil {
+		return nil, err
+	}
+	return &signatureFactory{
+		alg:             alg,
+		current:         current,
+		previous:        rotation.PreviousSecret,
+		previousExpires: expires,
+		now:             time.Now,
+		validations:     measures.Signatures,
+		replay:          newReplayGuard(config.Replay, measures.ReplayRejected),
+	}, nil
+}
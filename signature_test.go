@@ -0,0 +1,340 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1" // nolint:gosec
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"hash"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/v2/xmetrics/xmetricstest"
+)
+
+func TestGetHashAlgorithm(t *testing.T) {
+	tests := []struct {
+		name        string
+		expectedKey string
+		expectedErr error
+	}{
+		{name: "", expectedKey: "Sha1"},
+		{name: "sha1", expectedKey: "Sha1"},
+		{name: "SHA-256", expectedKey: "Sha256"},
+		{name: "Sha512", expectedKey: "Sha512"},
+		{name: "md5", expectedErr: errUnsupportedAlgorithm},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			alg, err := getHashAlgorithm(tc.name)
+			if tc.expectedErr != nil {
+				require.NotNil(t, err)
+				assert.Contains(err.Error(), tc.expectedErr.Error())
+			} else {
+				assert.Nil(err)
+			}
+			assert.Equal(tc.expectedKey, string(alg.key))
+			if tc.expectedErr == nil {
+				assert.NotNil(alg.newFunc)
+			}
+		})
+	}
+}
+
+func TestSignatureHeader(t *testing.T) {
+	assert := assert.New(t)
+	header, err := signatureHeader(SecretConfig{Header: "X-Webpa-Signature", Delimiter: "=", Algorithm: "sha-256"})
+	assert.Nil(err)
+	assert.Equal("X-Webpa-Signature: Sha256=<hex hmac>", header)
+
+	header, err = signatureHeader(SecretConfig{})
+	assert.Nil(err)
+	assert.Equal("Authorization: Sha1 <hex hmac>", header)
+
+	_, err = signatureHeader(SecretConfig{Algorithm: "md5"})
+	require.NotNil(t, err)
+	assert.Contains(err.Error(), errUnsupportedAlgorithm.Error())
+}
+
+func TestNewSignatureConstructor(t *testing.T) {
+	const (
+		secret    = "super secret"
+		oldSecret = "old secret"
+	)
+	body := []byte("test body")
+	signWith := func(newFunc func() hash.Hash, secret string) string {
+		h := hmac.New(newFunc, []byte(secret))
+		h.Write(body)
+		return hex.EncodeToString(h.Sum(nil))
+	}
+	sign := func(newFunc func() hash.Hash) string {
+		return signWith(newFunc, secret)
+	}
+	later := time.Now().Add(time.Hour).Format(time.RFC3339)
+	earlier := time.Now().Add(-time.Hour).Format(time.RFC3339)
+
+	tests := []struct {
+		description    string
+		algorithm      string
+		emptySecret    bool
+		rotation       SecretRotationConfig
+		signature      string
+		expectedErr    error
+		expectedCode   int
+		expectedSecret string
+	}{
+		{
+			description:    "Default Success",
+			signature:      "Sha1=" + sign(sha1.New),
+			expectedCode:   http.StatusOK,
+			expectedSecret: currentSecret,
+		},
+		{
+			description: "Current Secret During Rotation",
+			algorithm:   "sha256",
+			rotation: SecretRotationConfig{
+				PreviousSecret:        oldSecret,
+				PreviousSecretExpires: later,
+			},
+			signature:      "Sha256=" + sign(sha256.New),
+			expectedCode:   http.StatusOK,
+			expectedSecret: currentSecret,
+		},
+		{
+			description: "Previous Secret Before Expiry",
+			algorithm:   "sha256",
+			rotation: SecretRotationConfig{
+				PreviousSecret:        oldSecret,
+				PreviousSecretExpires: later,
+			},
+			signature:      "Sha256=" + signWith(sha256.New, oldSecret),
+			expectedCode:   http.StatusOK,
+			expectedSecret: previousSecret,
+		},
+		{
+			description: "Previous Secret After Expiry",
+			algorithm:   "sha256",
+			rotation: SecretRotationConfig{
+				PreviousSecret:        oldSecret,
+				PreviousSecretExpires: earlier,
+			},
+			signature:    "Sha256=" + signWith(sha256.New, oldSecret),
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			description:  "Previous Secret Not Configured",
+			algorithm:    "sha256",
+			signature:    "Sha256=" + signWith(sha256.New, oldSecret),
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			description:  "Bad Signature Encoding",
+			signature:    "Sha1=zzz",
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			description: "Empty Secret",
+			emptySecret: true,
+			expectedErr: errEmptySecret,
+		},
+		{
+			description: "No Expiry",
+			rotation:    SecretRotationConfig{PreviousSecret: oldSecret},
+			expectedErr: errNoSecretExpiry,
+		},
+		{
+			description: "Invalid Expiry",
+			rotation:    SecretRotationConfig{PreviousSecret: oldSecret, PreviousSecretExpires: "next week"},
+			expectedErr: errInvalidSecretExpiry,
+		},
+		{
+			description:    "Sha256 Success",
+			algorithm:      "sha256",
+			signature:      "Sha256=" + sign(sha256.New),
+			expectedCode:   http.StatusOK,
+			expectedSecret: currentSecret,
+		},
+		{
+			description:    "Sha512 Success",
+			algorithm:      "sha512",
+			signature:      "Sha512=" + sign(sha512.New),
+			expectedCode:   http.StatusOK,
+			expectedSecret: currentSecret,
+		},
+		{
+			description:  "Sha1 Signature With Sha256 Configured",
+			algorithm:    "sha256",
+			signature:    "Sha1=" + sign(sha1.New),
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			description:  "Wrong Hash",
+			algorithm:    "sha256",
+			signature:    "Sha256=" + sign(sha512.New),
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			description: "Unsupported Algorithm",
+			algorithm:   "md5",
+			expectedErr: errUnsupportedAlgorithm,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			config := SecretConfig{
+				Header:    "X-Webpa-Signature",
+				Delimiter: "=",
+				Algorithm: tc.algorithm,
+			}
+			current := secret
+			if tc.emptySecret {
+				current = ""
+			}
+			p := xmetricstest.NewProvider(nil, Metrics)
+			constructor, err := newSignatureConstructor(config, current, tc.rotation, NewMeasures(p))
+			if tc.expectedErr != nil {
+				require.NotNil(t, err)
+				assert.Contains(err.Error(), tc.expectedErr.Error())
+				assert.Nil(constructor)
+				return
+			}
+			assert.Nil(err)
+			require.NotNil(t, constructor)
+
+			handler := constructor(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+			request := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+			request.Header.Set("X-Webpa-Signature", tc.signature)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, request)
+			assert.Equal(tc.expectedCode, rr.Code)
+			for _, label := range []string{currentSecret, previousSecret} {
+				expected := 0.0
+				if label == tc.expectedSecret {
+					expected = 1.0
+				}
+				p.Assert(t, SignatureCounter, secretLabel, label)(xmetricstest.Value(expected))
+			}
+		})
+	}
+}
+
+func TestPreviousSecretExpires(t *testing.T) {
+	const (
+		secret    = "super secret"
+		oldSecret = "old secret"
+	)
+	expires := time.Date(2026, time.March, 1, 12, 0, 0, 0, time.UTC)
+	body := []byte("test body")
+	h := hmac.New(sha1.New, []byte(oldSecret))
+	h.Write(body)
+	signature := hex.EncodeToString(h.Sum(nil))
+
+	validate := func(factory *signatureFactory) error {
+		_, err := factory.ParseAndValidate(context.Background(), httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body)), "Sha1", signature)
+		return err
+	}
+
+	// whenever svalinn starts, or restarts, the previous secret is only
+	// accepted before it expires.
+	for _, started := range []time.Duration{-48 * time.Hour, -time.Second, time.Second, 48 * time.Hour} {
+		assert := assert.New(t)
+		clock := expires.Add(started)
+		factory, err := newSignatureFactory(SecretConfig{}, secret,
+			SecretRotationConfig{PreviousSecret: oldSecret, PreviousSecretExpires: expires.Format(time.RFC3339)}, NewMeasures(xmetricstest.NewProvider(nil, Metrics)))
+		require.Nil(t, err)
+		factory.now = func() time.Time { return clock }
+
+		if started < 0 {
+			assert.Nil(validate(factory), "started %v", started)
+		} else {
+			assert.NotNil(validate(factory), "started %v", started)
+		}
+		clock = expires.Add(time.Second)
+		assert.NotNil(validate(factory), "started %v", started)
+	}
+}
+
+func TestRegistrationRotation(t *testing.T) {
+	const (
+		secret          = "default secret"
+		oldSecret       = "old default secret"
+		rebootSecret    = "reboot secret"
+		oldRebootSecret = "old reboot secret"
+	)
+	body := []byte("test body")
+	sign := func(secret string) string {
+		h := hmac.New(sha1.New, []byte(secret))
+		h.Write(body)
+		return hex.EncodeToString(h.Sum(nil))
+	}
+	expires := time.Now().Add(time.Hour).Format(time.RFC3339)
+	config := &SvalinnConfig{
+		Endpoint: "/device-status",
+		Secret:   SecretConfig{Header: "X-Webpa-Signature", Delimiter: "="},
+	}
+	config.Secret.Rotation = SecretRotationConfig{PreviousSecret: oldSecret, PreviousSecretExpires: expires}
+	config.Webhook.Request.Events = []string{".*"}
+	config.Webhook.Request.Config.Secret = secret
+	config.Webhook.Registrations = []RegistrationConfig{{
+		Name:     "reboots",
+		Endpoint: "/reboots",
+		Rotation: SecretRotationConfig{PreviousSecret: oldRebootSecret, PreviousSecretExpires: expires},
+	}}
+	config.Webhook.Registrations[0].Request.Events = []string{".*"}
+	config.Webhook.Registrations[0].Request.Config.Secret = rebootSecret
+	registrations, err := newRegistrations(config, "http://svalinn.example.com")
+	require.Nil(t, err)
+	require.Len(t, registrations, 2)
+
+	// each registration only accepts its own previous secret.
+	accepted := map[string]map[string]bool{
+		defaultRegistrationName: {secret: true, oldSecret: true, rebootSecret: false, oldRebootSecret: false},
+		"reboots":               {secret: false, oldSecret: false, rebootSecret: true, oldRebootSecret: true},
+	}
+	for _, r := range registrations {
+		constructor, err := newSignatureConstructor(config.Secret, r.secret(), r.rotation, NewMeasures(xmetricstest.NewProvider(nil, Metrics)))
+		require.Nil(t, err)
+		handler := constructor(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		for signedWith, ok := range accepted[r.name] {
+			request := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+			request.Header.Set("X-Webpa-Signature", "Sha1="+sign(signedWith))
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, request)
+			expected := http.StatusUnauthorized
+			if ok {
+				expected = http.StatusOK
+			}
+			assert.Equal(t, expected, rr.Code, "registration %s signed with %q", r.name, signedWith)
+		}
+	}
+}
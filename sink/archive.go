@@ -0,0 +1,413 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package sink
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/goph/emperror"
+	"github.com/xmidt-org/codex-db"
+	"github.com/xmidt-org/webpa-common/v2/logging"
+)
+
+const (
+	defaultArchivePrefix  = "svalinn"
+	defaultArchiveMaxSize = 100 * 1024 * 1024
+	defaultArchiveMaxAge  = time.Hour
+
+	segmentExtension    = ".jsonl"
+	compressedExtension = ".gz"
+	segmentTimeFormat   = "20060102T150405.000000000Z"
+)
+
+var (
+	errNoArchiveDirectory = errors.New("archive has no directory")
+	errArchiveClosed      = errors.New("archive is closed")
+)
+
+// ArchiveConfig configures an Archive.
+type ArchiveConfig struct {
+	// Directory is where the segment files are written.  It's created if it
+	// doesn't exist.
+	Directory string
+
+	// Prefix starts the name of every segment file, so more than one archive
+	// can share a directory.  Defaults to svalinn.
+	Prefix string
+
+	// MaxSize is how many bytes a segment can hold before a new one is
+	// started.  Defaults to 100 MiB.
+	MaxSize int64
+
+	// MaxAge is how long a segment is written to before a new one is started.
+	// Defaults to an hour.
+	MaxAge time.Duration
+
+	// Compress gzips each segment once it's no longer written to.
+	Compress bool
+
+	// Retention is how long a segment is kept after it was last written to.
+	// If 0, segments are kept until MaxSegments is reached.
+	Retention time.Duration
+
+	// MaxSegments is how many segments are kept, besides the one being
+	// written to.  If 0, there is no limit.
+	MaxSegments int
+}
+
+// Archive is an append only sink that writes each record as a line of JSON to
+// local segment files.  Records are written as they're inserted into the
+// database, so their data is still encrypted.  Segments are started again
+// once they're too large or too old, and old segments are compressed and
+// removed in the background.
+type Archive struct {
+	config ArchiveConfig
+	logger log.Logger
+	now    func() time.Time
+
+	lock    sync.Mutex
+	file    *os.File
+	writer  *bufio.Writer
+	path    string
+	size    int64
+	started time.Time
+	closed  bool
+
+	// current is the path of the segment being written to, which cleanup
+	// reads without the lock so rotating never waits on it.
+	current atomic.Value
+
+	// rotated are the segments waiting for cleanup, which is woken through
+	// ready.  The queue isn't bounded, so rotating never waits on cleanup.
+	rotatedLock sync.Mutex
+	rotated     []rotatedSegment
+	ready       chan struct{}
+	done        chan struct{}
+}
+
+// NewArchive creates the archive's directory and starts its first segment.
+// Segments left uncompressed by an earlier run are compressed if Compress is
+// set.
+func NewArchive(config ArchiveConfig, logger log.Logger) (*Archive, error) {
+	if config.Directory == "" {
+		return nil, errNoArchiveDirectory
+	}
+	if config.Prefix == "" {
+		config.Prefix = defaultArchivePrefix
+	}
+	if config.MaxSize <= 0 {
+		config.MaxSize = defaultArchiveMaxSize
+	}
+	if config.MaxAge <= 0 {
+		config.MaxAge = defaultArchiveMaxAge
+	}
+	if logger == nil {
+		logger = logging.DefaultLogger()
+	}
+	if err := os.MkdirAll(config.Directory, 0755); err != nil {
+		return nil, emperror.WrapWith(err, "failed to create archive directory", "directory", config.Directory)
+	}
+	leftover, err := filepath.Glob(filepath.Join(config.Directory, config.Prefix+"-*"+segmentExtension))
+	if err != nil {
+		return nil, emperror.WrapWith(err, "failed to find archive segments", "directory", config.Directory)
+	}
+
+	a := &Archive{
+		config: config,
+		logger: logger,
+		now:    time.Now,
+		ready:  make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	if err := a.startSegment(); err != nil {
+		return nil, err
+	}
+	for _, path := range leftover {
+		a.queueRotated(rotatedSegment{path: path, at: a.started})
+	}
+	go a.cleanup()
+	return a, nil
+}
+
+// InsertRecords appends the records to the current segment and syncs it to
+// disk, starting a new segment first if the current one is too old or would
+// become too large.
+func (a *Archive) InsertRecords(records ...db.Record) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.closed {
+		return errArchiveClosed
+	}
+	// if starting a segment failed, there's no segment to write to yet.
+	if a.file == nil {
+		if err := a.startSegment(); err != nil {
+			return err
+		}
+	}
+	if a.now().Sub(a.started) >= a.config.MaxAge {
+		if err := a.rotate(); err != nil {
+			return err
+		}
+	}
+	for _, r := range records {
+		line, err := json.Marshal(r)
+		if err != nil {
+			return emperror.WrapWith(err, "failed to encode record", "device id", r.DeviceID)
+		}
+		line = append(line, '\n')
+		if a.size > 0 && a.size+int64(len(line)) > a.config.MaxSize {
+			if err := a.rotate(); err != nil {
+				return err
+			}
+		}
+		n, err := a.writer.Write(line)
+		a.size += int64(n)
+		if err != nil {
+			return emperror.WrapWith(err, "failed to write to archive segment", "path", a.path)
+		}
+	}
+	if err := a.writer.Flush(); err != nil {
+		return emperror.WrapWith(err, "failed to write to archive segment", "path", a.path)
+	}
+	if err := a.file.Sync(); err != nil {
+		return emperror.WrapWith(err, "failed to sync archive segment", "path", a.path)
+	}
+	return nil
+}
+
+// Close finishes the current segment and waits for the segments to be
+// compressed and removed.
+func (a *Archive) Close() error {
+	a.lock.Lock()
+	if a.closed {
+		a.lock.Unlock()
+		return nil
+	}
+	a.closed = true
+	a.current.Store("")
+	var err error
+	if a.file != nil {
+		err = a.closeSegment()
+	}
+	close(a.ready)
+	a.lock.Unlock()
+
+	<-a.done
+	return err
+}
+
+// rotate finishes the current segment and starts a new one.  The lock must be
+// held.
+func (a *Archive) rotate() error {
+	if err := a.closeSegment(); err != nil {
+		return err
+	}
+	return a.startSegment()
+}
+
+func (a *Archive) startSegment() error {
+	started := a.now()
+	var (
+		path string
+		file *os.File
+		err  error
+	)
+	// segments are named by when they're started, so their names sort in the
+	// order they were written.
+	for t := started.UTC(); ; t = t.Add(time.Nanosecond) {
+		path = filepath.Join(a.config.Directory, a.config.Prefix+"-"+t.Format(segmentTimeFormat)+segmentExtension)
+		file, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if !os.IsExist(err) {
+			break
+		}
+	}
+	if err != nil {
+		return emperror.WrapWith(err, "failed to create archive segment", "path", path)
+	}
+	a.file, a.writer, a.path, a.size, a.started = file, bufio.NewWriter(file), path, 0, started
+	a.current.Store(path)
+	return nil
+}
+
+// closeSegment finishes the current segment, leaving no segment to write to
+// until one is started.  The lock must be held.
+func (a *Archive) closeSegment() error {
+	err := a.writer.Flush()
+	if closeErr := a.file.Close(); err == nil {
+		err = closeErr
+	}
+	a.file, a.writer = nil, nil
+	if err != nil {
+		return emperror.WrapWith(err, "failed to close archive segment", "path", a.path)
+	}
+	// a segment nothing was written to isn't worth keeping.
+	if a.size == 0 {
+		if err := os.Remove(a.path); err != nil {
+			return emperror.WrapWith(err, "failed to remove empty archive segment", "path", a.path)
+		}
+		return nil
+	}
+	a.queueRotated(rotatedSegment{path: a.path, at: a.now()})
+	return nil
+}
+
+// queueRotated queues a finished segment for cleanup.
+func (a *Archive) queueRotated(r rotatedSegment) {
+	a.rotatedLock.Lock()
+	a.rotated = append(a.rotated, r)
+	a.rotatedLock.Unlock()
+	select {
+	case a.ready <- struct{}{}:
+	default:
+	}
+}
+
+// takeRotated takes the segments waiting for cleanup.
+func (a *Archive) takeRotated() []rotatedSegment {
+	a.rotatedLock.Lock()
+	defer a.rotatedLock.Unlock()
+	rotated := a.rotated
+	a.rotated = nil
+	return rotated
+}
+
+// rotatedSegment is a segment that is no longer written to.
+type rotatedSegment struct {
+	path string
+	at   time.Time
+}
+
+// cleanup compresses the segments that are no longer written to and removes
+// the ones past retention, until the archive is closed.
+func (a *Archive) cleanup() {
+	defer close(a.done)
+	for open := true; open; {
+		// the segments queued before the archive was closed are still
+		// cleaned up.
+		_, open = <-a.ready
+		for _, r := range a.takeRotated() {
+			if a.config.Compress {
+				if err := compressSegment(r.path); err != nil {
+					logging.Error(a.logger, emperror.Context(err)...).Log(logging.MessageKey(), "Failed to compress archive segment",
+						"path", r.path, logging.ErrorKey(), err.Error())
+				}
+			}
+			if err := a.prune(r.at); err != nil {
+				logging.Error(a.logger, emperror.Context(err)...).Log(logging.MessageKey(), "Failed to remove old archive segments",
+					logging.ErrorKey(), err.Error())
+			}
+		}
+	}
+}
+
+// prune removes the finished segments that are older than the retention or
+// past the maximum number of segments, as of when a segment was finished.
+func (a *Archive) prune(now time.Time) error {
+	if a.config.Retention <= 0 && a.config.MaxSegments <= 0 {
+		return nil
+	}
+	current := a.current.Load().(string)
+	files, err := ioutil.ReadDir(a.config.Directory)
+	if err != nil {
+		return emperror.WrapWith(err, "failed to list archive segments", "directory", a.config.Directory)
+	}
+	var segments []os.FileInfo
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || !strings.HasPrefix(name, a.config.Prefix+"-") || filepath.Join(a.config.Directory, name) == current {
+			continue
+		}
+		if strings.HasSuffix(name, segmentExtension) || strings.HasSuffix(name, segmentExtension+compressedExtension) {
+			segments = append(segments, f)
+		}
+	}
+	// newest first.
+	sort.Slice(segments, func(i, j int) bool { return segments[i].Name() > segments[j].Name() })
+
+	cutoff := now.Add(-a.config.Retention)
+	for i, f := range segments {
+		expired := a.config.Retention > 0 && f.ModTime().Before(cutoff)
+		extra := a.config.MaxSegments > 0 && i >= a.config.MaxSegments
+		if !expired && !extra {
+			continue
+		}
+		path := filepath.Join(a.config.Directory, f.Name())
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return emperror.WrapWith(err, "failed to remove archive segment", "path", path)
+		}
+	}
+	return nil
+}
+
+// compressSegment gzips the segment into a temporary file and renames it, so
+// a partly compressed segment never replaces the original.
+func compressSegment(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return emperror.Wrap(err, "failed to open archive segment")
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return emperror.Wrap(err, "failed to check archive segment")
+	}
+
+	tmp := path + compressedExtension + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return emperror.Wrap(err, "failed to create compressed archive segment")
+	}
+	gz := gzip.NewWriter(out)
+	_, err = io.Copy(gz, in)
+	if err == nil {
+		err = gz.Close()
+	}
+	if err == nil {
+		err = out.Sync()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return emperror.Wrap(err, "failed to compress archive segment")
+	}
+	// keep when the segment was last written to, which retention is based on.
+	if err := os.Chtimes(tmp, info.ModTime(), info.ModTime()); err != nil {
+		os.Remove(tmp)
+		return emperror.Wrap(err, "failed to compress archive segment")
+	}
+	if err := os.Rename(tmp, path+compressedExtension); err != nil {
+		os.Remove(tmp)
+		return emperror.Wrap(err, "failed to compress archive segment")
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,220 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package sink
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/codex-db"
+	"github.com/xmidt-org/webpa-common/v2/logging"
+)
+
+func TestArchive(t *testing.T) {
+	records := []db.Record{
+		{Type: db.State, DeviceID: "mac:112233445566", BirthDate: 1, DeathDate: 2, Data: []byte("encrypted"), Alg: "box", KID: "key"},
+		{Type: db.Default, DeviceID: "mac:aabbccddeeff", BirthDate: 3, DeathDate: 4, Data: []byte("also encrypted")},
+		{Type: db.State, DeviceID: "mac:112233445566", BirthDate: 5, DeathDate: 6, Data: []byte("more")},
+	}
+
+	tests := []struct {
+		description      string
+		config           ArchiveConfig
+		oneAtATime       bool
+		later            time.Duration
+		leftover         bool
+		expectedSegments int
+		expectedRecords  int
+	}{
+		{
+			description:      "One Segment",
+			expectedSegments: 1,
+			expectedRecords:  3,
+		},
+		{
+			description:      "Size Rotation",
+			config:           ArchiveConfig{MaxSize: 10, Compress: true},
+			expectedSegments: 3,
+			expectedRecords:  3,
+		},
+		{
+			description:      "Age Rotation",
+			config:           ArchiveConfig{MaxAge: time.Minute},
+			oneAtATime:       true,
+			later:            time.Hour,
+			expectedSegments: 3,
+			expectedRecords:  3,
+		},
+		{
+			description:      "Max Segments",
+			config:           ArchiveConfig{MaxSize: 10, MaxSegments: 1},
+			expectedSegments: 1,
+			expectedRecords:  1,
+		},
+		{
+			description:      "Retention",
+			config:           ArchiveConfig{MaxSize: 10, Retention: time.Minute},
+			later:            time.Hour,
+			expectedSegments: 0,
+		},
+		{
+			description:      "Compress Leftover Segment",
+			config:           ArchiveConfig{Compress: true},
+			leftover:         true,
+			expectedSegments: 2,
+			expectedRecords:  4,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			dir, err := ioutil.TempDir("", "archive")
+			require.Nil(err)
+			defer os.RemoveAll(dir)
+
+			if tc.leftover {
+				line, err := json.Marshal(records[0])
+				require.Nil(err)
+				require.Nil(ioutil.WriteFile(filepath.Join(dir, "svalinn-20190101T000000.000000000Z.jsonl"), append(line, '\n'), 0644))
+			}
+			config := tc.config
+			config.Directory = dir
+			a, err := NewArchive(config, logging.NewTestLogger(nil, t))
+			require.Nil(err)
+			if tc.later > 0 {
+				// each reading of the clock is later than the last.
+				now := time.Now()
+				a.now = func() time.Time {
+					now = now.Add(tc.later)
+					return now
+				}
+			}
+
+			if tc.oneAtATime {
+				for _, r := range records {
+					assert.Nil(a.InsertRecords(r))
+				}
+			} else {
+				assert.Nil(a.InsertRecords(records...))
+			}
+			assert.Nil(a.Close())
+			assert.Equal(errArchiveClosed, a.InsertRecords(records...))
+
+			segments, archived := readArchive(t, dir)
+			assert.Len(segments, tc.expectedSegments)
+			assert.Len(archived, tc.expectedRecords)
+			for _, s := range segments {
+				assert.Equal(config.Compress, strings.HasSuffix(s, compressedExtension), s)
+			}
+			if tc.expectedRecords == len(records) {
+				assert.Equal(records, archived)
+			}
+		})
+	}
+}
+
+func TestArchiveFailedRotation(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	records := []db.Record{
+		{Type: db.State, DeviceID: "mac:112233445566", BirthDate: 1, DeathDate: 2, Data: []byte("encrypted")},
+		{Type: db.State, DeviceID: "mac:112233445566", BirthDate: 3, DeathDate: 4, Data: []byte("more")},
+	}
+	dir, err := ioutil.TempDir("", "archive")
+	require.Nil(err)
+	defer os.RemoveAll(dir)
+
+	a, err := NewArchive(ArchiveConfig{Directory: dir, MaxAge: time.Minute, Compress: true}, logging.NewTestLogger(nil, t))
+	require.Nil(err)
+	now := time.Now()
+	a.now = func() time.Time { return now }
+	assert.Nil(a.InsertRecords(records[0]))
+
+	// the first segment is finished, but the next one can't be created.
+	a.lock.Lock()
+	a.config.Prefix = filepath.Join("missing", defaultArchivePrefix)
+	a.lock.Unlock()
+	now = now.Add(time.Hour)
+	err = a.InsertRecords(records[1])
+	require.NotNil(err)
+	assert.Contains(err.Error(), "failed to create archive segment")
+
+	// once segments can be created again, the archive picks up where it left
+	// off without touching the finished segment.
+	a.lock.Lock()
+	a.config.Prefix = defaultArchivePrefix
+	a.lock.Unlock()
+	assert.Nil(a.InsertRecords(records[1]))
+	assert.Nil(a.Close())
+
+	segments, archived := readArchive(t, dir)
+	assert.Len(segments, 2)
+	for _, s := range segments {
+		assert.True(strings.HasSuffix(s, compressedExtension), s)
+	}
+	assert.Equal(records, archived)
+}
+
+func TestNewArchiveNoDirectory(t *testing.T) {
+	_, err := NewArchive(ArchiveConfig{}, nil)
+	assert.Equal(t, errNoArchiveDirectory, err)
+}
+
+// readArchive gets the archive's segments in order and the records in them.
+func readArchive(t *testing.T, dir string) ([]string, []db.Record) {
+	files, err := ioutil.ReadDir(dir)
+	require.Nil(t, err)
+	var segments []string
+	for _, f := range files {
+		segments = append(segments, f.Name())
+	}
+	sort.Strings(segments)
+
+	var records []db.Record
+	for _, s := range segments {
+		f, err := os.Open(filepath.Join(dir, s))
+		require.Nil(t, err)
+		var r io.Reader = f
+		if strings.HasSuffix(s, compressedExtension) {
+			r, err = gzip.NewReader(f)
+			require.Nil(t, err)
+		}
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			var record db.Record
+			require.Nil(t, json.Unmarshal(scanner.Bytes(), &record))
+			records = append(records, record)
+		}
+		require.Nil(t, scanner.Err())
+		f.Close()
+	}
+	return segments, records
+}
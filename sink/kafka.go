@@ -0,0 +1,214 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package sink
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/goph/emperror"
+	"github.com/xmidt-org/codex-db"
+)
+
+const (
+	defaultKafkaClientID = "svalinn"
+
+	acksNone   = "none"
+	acksLeader = "leader"
+	acksAll    = "all"
+)
+
+var (
+	errNoBrokers      = errors.New("kafka sink has no brokers")
+	errNoTopic        = errors.New("kafka sink has no topic")
+	errUnknownAcks    = errors.New("unknown kafka acks")
+	errUnknownCodec   = errors.New("unknown kafka compression")
+	errInvalidVersion = errors.New("invalid kafka version")
+	errNotDelivered   = errors.New("records weren't delivered to kafka")
+)
+
+// KafkaConfig configures a Kafka sink.
+type KafkaConfig struct {
+	// Brokers are the addresses used to find the rest of the cluster.
+	Brokers []string
+
+	// Topic is where the records are published.
+	Topic string
+
+	// ClientID identifies svalinn to the brokers.  Defaults to svalinn.
+	ClientID string
+
+	// Version is the Kafka version the brokers support, such as 2.8.0.
+	// Defaults to the oldest version the client fully supports.
+	Version string
+
+	// Acks is how many replicas must have a batch before it's delivered: none,
+	// leader, or all.  Defaults to all.
+	Acks string
+
+	// Compression is none, gzip, snappy, lz4, or zstd.  Defaults to none.
+	Compression string
+
+	// FlushMessages, FlushBytes, and FlushFrequency are how many messages,
+	// how many bytes, or how long the producer waits for before sending what
+	// it has.  If none are set, messages are sent as soon as possible.
+	FlushMessages  int
+	FlushBytes     int
+	FlushFrequency time.Duration
+
+	// MaxRetries is how many times a message is sent again before it fails.
+	// Defaults to 3.
+	MaxRetries int
+
+	// Timeout is how long the brokers wait for the acks, and how long the
+	// client waits on the brokers.  Defaults to 10s.
+	Timeout time.Duration
+}
+
+// Kafka is a sink that publishes each record as JSON to a topic, keyed by its
+// device id so a device's records stay in order on one partition.  Records
+// are published as they're inserted into the database, so their data is
+// still encrypted.
+type Kafka struct {
+	name     string
+	topic    string
+	producer sarama.SyncProducer
+	measures *Measures
+}
+
+// NewKafka connects to the brokers and creates a Kafka sink.  The name labels
+// the sink's delivery metrics.
+func NewKafka(name string, config KafkaConfig, measures *Measures) (*Kafka, error) {
+	saramaConfig, err := newSaramaConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	producer, err := sarama.NewSyncProducer(config.Brokers, saramaConfig)
+	if err != nil {
+		return nil, emperror.WrapWith(err, "failed to create kafka producer", "brokers", strings.Join(config.Brokers, ","))
+	}
+	return &Kafka{
+		name:     name,
+		topic:    config.Topic,
+		producer: producer,
+		measures: measures,
+	}, nil
+}
+
+func newSaramaConfig(config KafkaConfig) (*sarama.Config, error) {
+	switch {
+	case len(config.Brokers) == 0:
+		return nil, errNoBrokers
+	case config.Topic == "":
+		return nil, errNoTopic
+	}
+
+	c := sarama.NewConfig()
+	c.ClientID = defaultKafkaClientID
+	if config.ClientID != "" {
+		c.ClientID = config.ClientID
+	}
+	if config.Version != "" {
+		version, err := sarama.ParseKafkaVersion(config.Version)
+		if err != nil {
+			return nil, emperror.WrapWith(errInvalidVersion, err.Error(), "version", config.Version)
+		}
+		c.Version = version
+	}
+
+	switch strings.ToLower(config.Acks) {
+	case acksNone:
+		c.Producer.RequiredAcks = sarama.NoResponse
+	case acksLeader:
+		c.Producer.RequiredAcks = sarama.WaitForLocal
+	case "", acksAll:
+		c.Producer.RequiredAcks = sarama.WaitForAll
+	default:
+		return nil, emperror.With(errUnknownAcks, "acks", config.Acks)
+	}
+	if config.Compression != "" {
+		if err := c.Producer.Compression.UnmarshalText([]byte(strings.ToLower(config.Compression))); err != nil {
+			return nil, emperror.With(errUnknownCodec, "compression", config.Compression)
+		}
+	}
+
+	c.Producer.Flush.Messages = config.FlushMessages
+	c.Producer.Flush.Bytes = config.FlushBytes
+	c.Producer.Flush.Frequency = config.FlushFrequency
+	if config.MaxRetries > 0 {
+		c.Producer.Retry.Max = config.MaxRetries
+	}
+	if config.Timeout > 0 {
+		c.Producer.Timeout = config.Timeout
+		c.Net.DialTimeout = config.Timeout
+		c.Net.ReadTimeout = config.Timeout
+		c.Net.WriteTimeout = config.Timeout
+	}
+	// records are keyed by device id, so a device's records go to the same
+	// partition.
+	c.Producer.Partitioner = sarama.NewHashPartitioner
+	c.Producer.Return.Successes = true
+	c.Producer.Return.Errors = true
+
+	if err := c.Validate(); err != nil {
+		return nil, emperror.Wrap(err, "invalid kafka config")
+	}
+	return c, nil
+}
+
+// InsertRecords publishes the records and waits for them to be delivered.  If
+// any of them aren't, an error is returned.
+func (k *Kafka) InsertRecords(records ...db.Record) error {
+	messages := make([]*sarama.ProducerMessage, 0, len(records))
+	for _, r := range records {
+		value, err := json.Marshal(r)
+		if err != nil {
+			return emperror.WrapWith(err, "failed to encode record", "device id", r.DeviceID)
+		}
+		messages = append(messages, &sarama.ProducerMessage{
+			Topic: k.topic,
+			Key:   sarama.StringEncoder(r.DeviceID),
+			Value: sarama.ByteEncoder(value),
+		})
+	}
+
+	err := k.producer.SendMessages(messages)
+	failed := 0
+	if err != nil {
+		failed = len(messages)
+		if errs, ok := err.(sarama.ProducerErrors); ok {
+			failed = len(errs)
+		}
+	}
+	if k.measures != nil {
+		k.measures.KafkaMessages.With(sinkLabel, k.name, outcomeLabel, successOutcome).Add(float64(len(messages) - failed))
+		k.measures.KafkaMessages.With(sinkLabel, k.name, outcomeLabel, failureOutcome).Add(float64(failed))
+	}
+	if err != nil {
+		return emperror.WrapWith(errNotDelivered, err.Error(), "topic", k.topic, "failed", failed, "records", len(messages))
+	}
+	return nil
+}
+
+// Close sends the messages that are waiting and disconnects from the brokers.
+func (k *Kafka) Close() error {
+	return k.producer.Close()
+}
@@ -0,0 +1,146 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package sink
+
+import (
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/codex-db"
+	"github.com/xmidt-org/webpa-common/v2/xmetrics/xmetricstest"
+)
+
+func TestNewSaramaConfig(t *testing.T) {
+	tests := []struct {
+		description string
+		config      KafkaConfig
+		expectedErr error
+	}{
+		{
+			description: "Success",
+			config: KafkaConfig{
+				Brokers:     []string{"localhost:9092"},
+				Topic:       "events",
+				Version:     "2.8.0",
+				Acks:        "Leader",
+				Compression: "gzip",
+				Timeout:     time.Second,
+			},
+		},
+		{
+			description: "No Brokers",
+			config:      KafkaConfig{Topic: "events"},
+			expectedErr: errNoBrokers,
+		},
+		{
+			description: "No Topic",
+			config:      KafkaConfig{Brokers: []string{"localhost:9092"}},
+			expectedErr: errNoTopic,
+		},
+		{
+			description: "Invalid Version",
+			config:      KafkaConfig{Brokers: []string{"localhost:9092"}, Topic: "events", Version: "latest"},
+			expectedErr: errInvalidVersion,
+		},
+		{
+			description: "Unknown Acks",
+			config:      KafkaConfig{Brokers: []string{"localhost:9092"}, Topic: "events", Acks: "some"},
+			expectedErr: errUnknownAcks,
+		},
+		{
+			description: "Unknown Compression",
+			config:      KafkaConfig{Brokers: []string{"localhost:9092"}, Topic: "events", Compression: "zip"},
+			expectedErr: errUnknownCodec,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			c, err := newSaramaConfig(tc.config)
+			if tc.expectedErr != nil {
+				require.NotNil(t, err)
+				assert.Contains(err.Error(), tc.expectedErr.Error())
+				return
+			}
+			require.Nil(t, err)
+			assert.Equal(sarama.WaitForLocal, c.Producer.RequiredAcks)
+			assert.Equal(sarama.CompressionGZIP, c.Producer.Compression)
+			assert.Equal(sarama.V2_8_0_0, c.Version)
+			assert.Equal(time.Second, c.Producer.Timeout)
+		})
+	}
+}
+
+func TestKafka(t *testing.T) {
+	records := []db.Record{
+		{Type: db.State, DeviceID: "mac:112233445566", Data: []byte("encrypted")},
+		{Type: db.State, DeviceID: "mac:aabbccddeeff", Data: []byte("also encrypted")},
+	}
+	tests := []struct {
+		description string
+		produceErr  sarama.KError
+		expectedErr error
+	}{
+		{
+			description: "Success",
+			produceErr:  sarama.ErrNoError,
+		},
+		{
+			description: "Not Delivered",
+			produceErr:  sarama.ErrInvalidMessage,
+			expectedErr: errNotDelivered,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			broker := sarama.NewMockBroker(t, 1)
+			defer broker.Close()
+			broker.SetHandlerByMap(map[string]sarama.MockResponse{
+				"MetadataRequest": sarama.NewMockMetadataResponse(t).
+					SetBroker(broker.Addr(), broker.BrokerID()).
+					SetLeader("events", 0, broker.BrokerID()),
+				"ProduceRequest": sarama.NewMockProduceResponse(t).
+					SetError("events", 0, tc.produceErr),
+			})
+
+			p := xmetricstest.NewProvider(nil, Metrics)
+			k, err := NewKafka("bus", KafkaConfig{
+				Brokers:    []string{broker.Addr()},
+				Topic:      "events",
+				MaxRetries: 1,
+			}, NewMeasures(p))
+			require.Nil(err)
+
+			err = k.InsertRecords(records...)
+			if tc.expectedErr != nil {
+				require.NotNil(err)
+				assert.Contains(err.Error(), tc.expectedErr.Error())
+				p.Assert(t, KafkaMessagesCounter, sinkLabel, "bus", outcomeLabel, failureOutcome)(xmetricstest.Value(2.0))
+			} else {
+				assert.Nil(err)
+				p.Assert(t, KafkaMessagesCounter, sinkLabel, "bus", outcomeLabel, successOutcome)(xmetricstest.Value(2.0))
+			}
+			assert.Nil(k.Close())
+		})
+	}
+}
@@ -0,0 +1,78 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package sink
+
+import (
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/provider"
+	"github.com/xmidt-org/webpa-common/v2/xmetrics"
+)
+
+const (
+	SinkRecordsCounter   = "sink_records_count"
+	SinkInsertDuration   = "sink_insert_duration_seconds"
+	KafkaMessagesCounter = "sink_kafka_messages_count"
+)
+
+const (
+	sinkLabel    = "sink"
+	outcomeLabel = "outcome"
+
+	successOutcome = "success"
+	failureOutcome = "failure"
+	droppedOutcome = "dropped"
+)
+
+func Metrics() []xmetrics.Metric {
+	return []xmetrics.Metric{
+		{
+			Name:       SinkRecordsCounter,
+			Help:       "The number of records written to each sink, by whether the write succeeded, or dropped because the sink's queue was full",
+			Type:       "counter",
+			LabelNames: []string{sinkLabel, outcomeLabel},
+		},
+		{
+			Name:       SinkInsertDuration,
+			Help:       "How long writing a batch of records to each sink took, in seconds",
+			Type:       "histogram",
+			Buckets:    []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+			LabelNames: []string{sinkLabel},
+		},
+		{
+			Name:       KafkaMessagesCounter,
+			Help:       "The number of records each kafka sink published, by whether the brokers acknowledged them",
+			Type:       "counter",
+			LabelNames: []string{sinkLabel, outcomeLabel},
+		},
+	}
+}
+
+type Measures struct {
+	Records        metrics.Counter
+	InsertDuration metrics.Histogram
+	KafkaMessages  metrics.Counter
+}
+
+// NewMeasures constructs a Measures given a go-kit metrics Provider
+func NewMeasures(p provider.Provider) *Measures {
+	return &Measures{
+		Records:        p.NewCounter(SinkRecordsCounter),
+		InsertDuration: p.NewHistogram(SinkInsertDuration, 10),
+		KafkaMessages:  p.NewCounter(KafkaMessagesCounter),
+	}
+}
@@ -0,0 +1,266 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package sink writes records to more than one destination: the database
+// along with secondary sinks such as archives or message buses.
+package sink
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/goph/emperror"
+	"github.com/xmidt-org/codex-db"
+	"github.com/xmidt-org/webpa-common/v2/logging"
+)
+
+const (
+	// PrimaryName is the sink label of the database.
+	PrimaryName = "primary"
+
+	// IgnoreFailures only counts a secondary sink's failures in its metrics.
+	// The sink is written to in the background, so the batch doesn't wait
+	// for it.
+	IgnoreFailures = "ignore"
+
+	// DropOnFailure treats a secondary sink's failure like a failure of the
+	// primary sink, so the batch is counted as dropped.
+	DropOnFailure = "drop"
+
+	defaultQueueSize = 10
+)
+
+var (
+	errUnknownFailurePolicy = errors.New("unknown sink failure policy")
+	errNoPrimary            = errors.New("no primary sink")
+	errNoSinkName           = errors.New("sink has no name")
+	errDuplicateSink        = errors.New("sink name is used more than once")
+)
+
+// Secondary is a sink records are written to along with the primary sink.
+type Secondary struct {
+	Name     string
+	Inserter db.Inserter
+
+	// FailurePolicy is ignore or drop.
+	FailurePolicy string
+
+	// QueueSize is how many batches can wait to be written to a sink that
+	// ignores failures.  Batches that don't fit are dropped for the sink.
+	// Defaults to 10.
+	QueueSize int
+}
+
+type secondary struct {
+	name          string
+	inserter      db.Inserter
+	dropOnFailure bool
+
+	// queue holds the batches waiting for a sink that ignores failures.
+	queue chan []db.Record
+}
+
+// Fanout writes each batch of records to the primary sink and every secondary
+// sink with the drop policy at the same time, and queues it for the secondary
+// sinks that ignore failures.  A failure of the primary sink, or of a
+// secondary sink with the drop policy, fails the batch.
+type Fanout struct {
+	primaryName string
+	primary     db.Inserter
+	secondaries []secondary
+	measures    *Measures
+	logger      log.Logger
+
+	lock    sync.RWMutex
+	closed  bool
+	writers sync.WaitGroup
+}
+
+// NewFanout creates a Fanout.  The primary name labels the primary sink's
+// metrics.  If there are no secondary sinks, the primary sink can be used
+// directly instead.
+func NewFanout(primaryName string, primary db.Inserter, secondaries []Secondary, measures *Measures, logger log.Logger) (*Fanout, error) {
+	if primary == nil {
+		return nil, errNoPrimary
+	}
+	if primaryName == "" {
+		primaryName = PrimaryName
+	}
+	if logger == nil {
+		logger = logging.DefaultLogger()
+	}
+	f := &Fanout{
+		primaryName: primaryName,
+		primary:     primary,
+		measures:    measures,
+		logger:      logger,
+	}
+	names := map[string]bool{primaryName: true}
+	for _, s := range secondaries {
+		switch {
+		case s.Name == "":
+			return nil, errNoSinkName
+		case names[s.Name]:
+			return nil, emperror.With(errDuplicateSink, "name", s.Name)
+		}
+		names[s.Name] = true
+		dropOnFailure := false
+		switch strings.ToLower(s.FailurePolicy) {
+		case "", IgnoreFailures:
+		case DropOnFailure:
+			dropOnFailure = true
+		default:
+			return nil, emperror.With(errUnknownFailurePolicy, "name", s.Name, "policy", s.FailurePolicy)
+		}
+		f.secondaries = append(f.secondaries, secondary{
+			name:          s.Name,
+			inserter:      s.Inserter,
+			dropOnFailure: dropOnFailure,
+		})
+	}
+	for i := range f.secondaries {
+		s := &f.secondaries[i]
+		if s.dropOnFailure {
+			continue
+		}
+		size := secondaries[i].QueueSize
+		if size <= 0 {
+			size = defaultQueueSize
+		}
+		s.queue = make(chan []db.Record, size)
+		f.writers.Add(1)
+		go f.write(*s)
+	}
+	return f, nil
+}
+
+// InsertRecords writes the records to the primary sink and the secondary sinks
+// that drop on failure, returning the error of the primary sink or else of
+// the first of those secondary sinks that failed.  The records are queued for
+// the sinks that ignore failures, without waiting for them to be written.
+func (f *Fanout) InsertRecords(records ...db.Record) error {
+	f.enqueue(records)
+	errs := make([]error, len(f.secondaries))
+	var wg sync.WaitGroup
+	for i, s := range f.secondaries {
+		if !s.dropOnFailure {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, s secondary) {
+			defer wg.Done()
+			errs[i] = f.insert(s.name, s.inserter, records)
+		}(i, s)
+	}
+	err := f.insert(f.primaryName, f.primary, records)
+	wg.Wait()
+
+	for i, s := range f.secondaries {
+		if errs[i] == nil {
+			continue
+		}
+		logging.Error(f.logger, emperror.Context(errs[i])...).Log(logging.MessageKey(), "Failed to write records to sink",
+			"sink", s.name, "records", len(records), "dropOnFailure", s.dropOnFailure, logging.ErrorKey(), errs[i].Error())
+		if err == nil {
+			err = emperror.WrapWith(errs[i], "secondary sink failed", "sink", s.name)
+		}
+	}
+	return err
+}
+
+// enqueue queues the records for each sink that ignores failures.  If a
+// sink's queue is full, or the Fanout is closed, the records are dropped for
+// that sink and counted in its metrics.
+func (f *Fanout) enqueue(records []db.Record) {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	for _, s := range f.secondaries {
+		if s.queue == nil {
+			continue
+		}
+		if !f.closed {
+			select {
+			case s.queue <- records:
+				continue
+			default:
+			}
+		}
+		if f.measures != nil {
+			f.measures.Records.With(sinkLabel, s.name, outcomeLabel, droppedOutcome).Add(float64(len(records)))
+		}
+		logging.Warn(f.logger).Log(logging.MessageKey(), "Sink queue is full, dropping records for the sink",
+			"sink", s.name, "records", len(records), "closed", f.closed)
+	}
+}
+
+// write writes the queued batches to a sink that ignores failures until the
+// Fanout is closed.
+func (f *Fanout) write(s secondary) {
+	defer f.writers.Done()
+	for records := range s.queue {
+		if err := f.insert(s.name, s.inserter, records); err != nil {
+			logging.Error(f.logger, emperror.Context(err)...).Log(logging.MessageKey(), "Failed to write records to sink",
+				"sink", s.name, "records", len(records), "dropOnFailure", s.dropOnFailure, logging.ErrorKey(), err.Error())
+		}
+	}
+}
+
+func (f *Fanout) insert(name string, inserter db.Inserter, records []db.Record) error {
+	start := time.Now()
+	err := inserter.InsertRecords(records...)
+	if f.measures != nil {
+		f.measures.InsertDuration.With(sinkLabel, name).Observe(time.Since(start).Seconds())
+		outcome := successOutcome
+		if err != nil {
+			outcome = failureOutcome
+		}
+		f.measures.Records.With(sinkLabel, name, outcomeLabel, outcome).Add(float64(len(records)))
+	}
+	return err
+}
+
+// Close waits for the queued batches to be written and closes the secondary
+// sinks that can be closed.  The primary sink is left to its owner.
+func (f *Fanout) Close() error {
+	f.lock.Lock()
+	if !f.closed {
+		f.closed = true
+		for _, s := range f.secondaries {
+			if s.queue != nil {
+				close(s.queue)
+			}
+		}
+	}
+	f.lock.Unlock()
+	f.writers.Wait()
+
+	var err error
+	for _, s := range f.secondaries {
+		c, ok := s.inserter.(io.Closer)
+		if !ok {
+			continue
+		}
+		if closeErr := c.Close(); closeErr != nil && err == nil {
+			err = emperror.WrapWith(closeErr, "failed to close sink", "sink", s.name)
+		}
+	}
+	return err
+}
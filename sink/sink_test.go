@@ -0,0 +1,207 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package sink
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/codex-db"
+	"github.com/xmidt-org/webpa-common/v2/logging"
+	"github.com/xmidt-org/webpa-common/v2/xmetrics/xmetricstest"
+)
+
+func TestNewFanout(t *testing.T) {
+	tests := []struct {
+		description string
+		primary     db.Inserter
+		secondaries []Secondary
+		expectedErr error
+	}{
+		{
+			description: "Success",
+			primary:     new(mockInserter),
+			secondaries: []Secondary{
+				{Name: "archive", Inserter: new(mockInserter)},
+				{Name: "bus", Inserter: new(mockInserter), FailurePolicy: "Drop"},
+			},
+		},
+		{
+			description: "No Primary",
+			expectedErr: errNoPrimary,
+		},
+		{
+			description: "No Name",
+			primary:     new(mockInserter),
+			secondaries: []Secondary{{Inserter: new(mockInserter)}},
+			expectedErr: errNoSinkName,
+		},
+		{
+			description: "Duplicate Name",
+			primary:     new(mockInserter),
+			secondaries: []Secondary{{Name: PrimaryName, Inserter: new(mockInserter)}},
+			expectedErr: errDuplicateSink,
+		},
+		{
+			description: "Unknown Policy",
+			primary:     new(mockInserter),
+			secondaries: []Secondary{{Name: "archive", Inserter: new(mockInserter), FailurePolicy: "retry"}},
+			expectedErr: errUnknownFailurePolicy,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			f, err := NewFanout(PrimaryName, tc.primary, tc.secondaries, nil, logging.NewTestLogger(nil, t))
+			if tc.expectedErr != nil {
+				require.NotNil(t, err)
+				assert.Contains(t, err.Error(), tc.expectedErr.Error())
+				return
+			}
+			require.Nil(t, err)
+			require.Len(t, f.secondaries, len(tc.secondaries))
+			assert.False(t, f.secondaries[0].dropOnFailure)
+			assert.True(t, f.secondaries[1].dropOnFailure)
+		})
+	}
+}
+
+func TestFanoutInsertRecords(t *testing.T) {
+	errSink := errors.New("test sink error")
+	records := []db.Record{{DeviceID: "mac:112233445566"}, {DeviceID: "mac:665544332211"}}
+	tests := []struct {
+		description string
+		primaryErr  error
+		ignoredErr  error
+		droppingErr error
+		expectedErr error
+	}{
+		{
+			description: "Success",
+		},
+		{
+			description: "Primary Fails",
+			primaryErr:  errSink,
+			expectedErr: errSink,
+		},
+		{
+			description: "Ignored Secondary Fails",
+			ignoredErr:  errSink,
+		},
+		{
+			description: "Dropping Secondary Fails",
+			droppingErr: errSink,
+			expectedErr: errSink,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			primary, ignored, dropping := new(mockInserter), new(mockInserter), new(mockInserter)
+			primary.On("InsertRecords", records).Return(tc.primaryErr).Once()
+			ignored.On("InsertRecords", records).Return(tc.ignoredErr).Once()
+			dropping.On("InsertRecords", records).Return(tc.droppingErr).Once()
+			p := xmetricstest.NewProvider(nil, Metrics)
+			f, err := NewFanout("", primary, []Secondary{
+				{Name: "ignored", Inserter: ignored, FailurePolicy: IgnoreFailures},
+				{Name: "dropping", Inserter: dropping, FailurePolicy: DropOnFailure},
+			}, NewMeasures(p), logging.NewTestLogger(nil, t))
+			require.Nil(t, err)
+
+			err = f.InsertRecords(records...)
+			if tc.expectedErr == nil {
+				assert.Nil(err)
+			} else {
+				require.NotNil(t, err)
+				assert.Contains(err.Error(), tc.expectedErr.Error())
+			}
+			// the ignored sink is written to in the background.
+			assert.Nil(f.Close())
+			primary.AssertExpectations(t)
+			ignored.AssertExpectations(t)
+			dropping.AssertExpectations(t)
+
+			outcome := func(err error) string {
+				if err != nil {
+					return failureOutcome
+				}
+				return successOutcome
+			}
+			p.Assert(t, SinkRecordsCounter, sinkLabel, PrimaryName, outcomeLabel, outcome(tc.primaryErr))(xmetricstest.Value(2.0))
+			p.Assert(t, SinkRecordsCounter, sinkLabel, "ignored", outcomeLabel, outcome(tc.ignoredErr))(xmetricstest.Value(2.0))
+			p.Assert(t, SinkRecordsCounter, sinkLabel, "dropping", outcomeLabel, outcome(tc.droppingErr))(xmetricstest.Value(2.0))
+		})
+	}
+}
+
+func TestFanoutQueuesIgnoredSinks(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	records := []db.Record{{DeviceID: "mac:112233445566"}, {DeviceID: "mac:665544332211"}}
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	primary, ignored := new(mockInserter), new(mockInserter)
+	primary.On("InsertRecords", records).Return(nil).Times(3)
+	ignored.On("InsertRecords", records).Return(nil).Run(func(mock.Arguments) {
+		started <- struct{}{}
+		<-release
+	}).Twice()
+	p := xmetricstest.NewProvider(nil, Metrics)
+	f, err := NewFanout("", primary, []Secondary{
+		{Name: "ignored", Inserter: ignored, QueueSize: 1},
+	}, NewMeasures(p), logging.NewTestLogger(nil, t))
+	require.Nil(err)
+
+	// the primary sink isn't held up by the ignored sink, which is writing
+	// the first batch and has the second one queued, so the third is dropped.
+	assert.Nil(f.InsertRecords(records...))
+	<-started
+	assert.Nil(f.InsertRecords(records...))
+	assert.Nil(f.InsertRecords(records...))
+	primary.AssertExpectations(t)
+	p.Assert(t, SinkRecordsCounter, sinkLabel, "ignored", outcomeLabel, droppedOutcome)(xmetricstest.Value(2.0))
+
+	close(release)
+	assert.Nil(f.Close())
+	ignored.AssertExpectations(t)
+	p.Assert(t, SinkRecordsCounter, sinkLabel, "ignored", outcomeLabel, successOutcome)(xmetricstest.Value(4.0))
+
+	// once closed, batches are dropped for the ignored sink.
+	primary.On("InsertRecords", records).Return(nil).Once()
+	assert.Nil(f.InsertRecords(records...))
+	p.Assert(t, SinkRecordsCounter, sinkLabel, "ignored", outcomeLabel, droppedOutcome)(xmetricstest.Value(4.0))
+}
+
+func TestFanoutClose(t *testing.T) {
+	errClose := errors.New("test close error")
+	primary, plain, closing := new(mockClosingInserter), new(mockInserter), new(mockClosingInserter)
+	closing.On("Close").Return(errClose).Once()
+	f, err := NewFanout("", primary, []Secondary{
+		{Name: "plain", Inserter: plain},
+		{Name: "closing", Inserter: closing},
+	}, nil, nil)
+	require.Nil(t, err)
+
+	err = f.Close()
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), errClose.Error())
+	closing.AssertExpectations(t)
+	primary.AssertNotCalled(t, "Close")
+}
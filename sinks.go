@@ -0,0 +1,138 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"errors"
+	"strings"
+
+	health "github.com/InVisionApp/go-health/v2"
+	"github.com/go-kit/kit/log"
+	"github.com/goph/emperror"
+	"github.com/xmidt-org/codex-db"
+	"github.com/xmidt-org/codex-db/cassandra"
+	"github.com/xmidt-org/svalinn/sink"
+	"github.com/xmidt-org/webpa-common/v2/xmetrics"
+)
+
+const (
+	sinkCassandra = "cassandra"
+	sinkArchive   = "archive"
+	sinkKafka     = "kafka"
+)
+
+var (
+	errUnknownSinkType = errors.New("unknown sink type")
+)
+
+// SinkConfig is a secondary destination the records are written to along
+// with the database.
+type SinkConfig struct {
+	// Name labels the sink's metrics and logs.
+	Name string
+
+	// Type is the kind of sink.
+	Type string
+
+	// FailurePolicy is ignore or drop.  With drop, a batch that fails to be
+	// written to the sink is counted as dropped, even if the database has it.
+	// With ignore, the sink is written to in the background.
+	FailurePolicy string
+
+	// QueueSize is how many batches can wait to be written to a sink that
+	// ignores failures before batches are dropped for it.  Defaults to 10.
+	QueueSize int
+
+	// Cassandra is the database the cassandra sink writes to.
+	Cassandra cassandra.Config
+
+	// Archive is the local files the archive sink writes to.
+	Archive sink.ArchiveConfig
+
+	// Kafka is the topic the kafka sink publishes to.
+	Kafka sink.KafkaConfig
+}
+
+// sinkOptions are what the sinks are created with besides their config.
+type sinkOptions struct {
+	metricsRegistry xmetrics.Registry
+	health          *health.Health
+	logger          log.Logger
+}
+
+// sinkFactory creates a sink, or returns an error if its config is invalid.
+type sinkFactory func(SinkConfig, sinkOptions) (db.Inserter, error)
+
+// sinkFactories are the sinks that can be configured, by type.
+var sinkFactories = map[string]sinkFactory{
+	sinkCassandra: newCassandraSink,
+	sinkArchive:   newArchiveSink,
+	sinkKafka:     newKafkaSink,
+}
+
+// newFanout creates the configured sinks and a Fanout writing to them along
+// with the primary inserter.  If no sinks are configured, the primary
+// inserter is returned without a Fanout.
+func newFanout(primaryName string, primary db.Inserter, configs []SinkConfig, options sinkOptions) (db.Inserter, func() error, error) {
+	if len(configs) == 0 {
+		return primary, func() error { return nil }, nil
+	}
+	var secondaries []sink.Secondary
+	for _, c := range configs {
+		inserter, err := newSink(c, options)
+		if err != nil {
+			return nil, nil, err
+		}
+		secondaries = append(secondaries, sink.Secondary{
+			Name:          c.Name,
+			Inserter:      inserter,
+			FailurePolicy: c.FailurePolicy,
+			QueueSize:     c.QueueSize,
+		})
+	}
+	fanout, err := sink.NewFanout(primaryName, primary, secondaries, sink.NewMeasures(options.metricsRegistry), options.logger)
+	if err != nil {
+		return nil, nil, err
+	}
+	return fanout, fanout.Close, nil
+}
+
+// newSink creates a sink of the configured type.
+func newSink(config SinkConfig, options sinkOptions) (db.Inserter, error) {
+	factory, ok := sinkFactories[strings.ToLower(config.Type)]
+	if !ok {
+		return nil, emperror.With(errUnknownSinkType, "name", config.Name, "type", config.Type)
+	}
+	inserter, err := factory(config, options)
+	if err != nil {
+		return nil, emperror.WrapWith(err, "failed to create sink", "name", config.Name, "type", config.Type)
+	}
+	return inserter, nil
+}
+
+func newCassandraSink(config SinkConfig, options sinkOptions) (db.Inserter, error) {
+	return cassandra.CreateDbConnection(config.Cassandra, options.metricsRegistry, options.health)
+}
+
+func newArchiveSink(config SinkConfig, options sinkOptions) (db.Inserter, error) {
+	return sink.NewArchive(config.Archive, options.logger)
+}
+
+func newKafkaSink(config SinkConfig, options sinkOptions) (db.Inserter, error) {
+	return sink.NewKafka(config.Name, config.Kafka, sink.NewMeasures(options.metricsRegistry))
+}
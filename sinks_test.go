@@ -0,0 +1,108 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/codex-db"
+	"github.com/xmidt-org/svalinn/sink"
+	"github.com/xmidt-org/webpa-common/v2/logging"
+	"github.com/xmidt-org/webpa-common/v2/xmetrics"
+)
+
+type testSink struct {
+	records []db.Record
+}
+
+func (s *testSink) InsertRecords(records ...db.Record) error {
+	s.records = append(s.records, records...)
+	return nil
+}
+
+func TestNewFanout(t *testing.T) {
+	errFactory := errors.New("test factory error")
+	secondary := new(testSink)
+	sinkFactories["test"] = func(config SinkConfig, _ sinkOptions) (db.Inserter, error) {
+		if config.Name == "broken" {
+			return nil, errFactory
+		}
+		return secondary, nil
+	}
+	defer delete(sinkFactories, "test")
+	registry, err := xmetrics.NewRegistry(nil, sink.Metrics)
+	require.Nil(t, err)
+	options := sinkOptions{metricsRegistry: registry, logger: logging.NewTestLogger(nil, t)}
+
+	t.Run("No Sinks", func(t *testing.T) {
+		primary := new(testSink)
+		inserter, closeSinks, err := newFanout(sink.PrimaryName, primary, nil, options)
+		assert.Nil(t, err)
+		assert.Equal(t, primary, inserter)
+		assert.Nil(t, closeSinks())
+	})
+
+	t.Run("Sinks", func(t *testing.T) {
+		assert := assert.New(t)
+		primary := new(testSink)
+		inserter, closeSinks, err := newFanout(sink.PrimaryName, primary, []SinkConfig{{Name: "archive", Type: "Test"}}, options)
+		require.Nil(t, err)
+		assert.IsType(&sink.Fanout{}, inserter)
+		assert.Nil(inserter.InsertRecords(db.Record{DeviceID: "mac:112233445566"}))
+		assert.Len(primary.records, 1)
+		// the sink ignores failures, so it's written to in the background.
+		assert.Nil(closeSinks())
+		assert.Len(secondary.records, 1)
+	})
+
+	t.Run("Archive", func(t *testing.T) {
+		assert := assert.New(t)
+		dir, err := ioutil.TempDir("", "archive")
+		require.Nil(t, err)
+		defer os.RemoveAll(dir)
+		config := SinkConfig{Name: "archive", Type: "archive", Archive: sink.ArchiveConfig{Directory: dir}}
+		inserter, closeSinks, err := newFanout(sink.PrimaryName, new(testSink), []SinkConfig{config}, options)
+		require.Nil(t, err)
+		assert.Nil(inserter.InsertRecords(db.Record{DeviceID: "mac:112233445566"}))
+		assert.Nil(closeSinks())
+		files, err := ioutil.ReadDir(dir)
+		assert.Nil(err)
+		assert.Len(files, 1)
+	})
+
+	t.Run("Unknown Type", func(t *testing.T) {
+		_, _, err := newFanout(sink.PrimaryName, new(testSink), []SinkConfig{{Name: "archive", Type: "tape"}}, options)
+		require.NotNil(t, err)
+		assert.Contains(t, err.Error(), errUnknownSinkType.Error())
+	})
+
+	t.Run("Factory Error", func(t *testing.T) {
+		_, _, err := newFanout(sink.PrimaryName, new(testSink), []SinkConfig{{Name: "broken", Type: "test"}}, options)
+		require.NotNil(t, err)
+		assert.Contains(t, err.Error(), errFactory.Error())
+	})
+
+	t.Run("Invalid Policy", func(t *testing.T) {
+		_, _, err := newFanout(sink.PrimaryName, new(testSink), []SinkConfig{{Name: "archive", Type: "test", FailurePolicy: "maybe"}}, options)
+		assert.NotNil(t, err)
+	})
+}
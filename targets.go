@@ -0,0 +1,207 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"errors"
+	"io"
+
+	"github.com/goph/emperror"
+	"github.com/xmidt-org/codex-db"
+	"github.com/xmidt-org/codex-db/batchInserter"
+	"github.com/xmidt-org/svalinn/requestParser"
+	"github.com/xmidt-org/svalinn/rules"
+	"github.com/xmidt-org/svalinn/sink"
+)
+
+var (
+	errNoTargetName      = errors.New("target has no name")
+	errDuplicateTarget   = errors.New("target name is used more than once")
+	errUnknownTarget     = errors.New("rule names a target that isn't configured")
+	errDuplicateSinkName = errors.New("sink name is used more than once")
+)
+
+// TargetConfig is somewhere other than the database that rules can store
+// their records, such as a cheaper cluster for high volume events.
+type TargetConfig struct {
+	// Name is what rules set as their target.  It also labels the metrics of
+	// the target's sink.
+	Name string
+
+	// Sink is where the target's records are stored, configured like the
+	// secondary sinks.  Its name is the target's name.
+	Sink SinkConfig
+
+	// Sinks are written to along with the target's sink.
+	Sinks []SinkConfig
+
+	// BatchInserter batches the target's records before they're written.
+	BatchInserter batchInserter.Config
+}
+
+// target has its own batch inserter writing to its sinks.
+type target struct {
+	name          string
+	batchInserter *batchInserter.BatchInserter
+	closeSinks    func() error
+}
+
+type targets []*target
+
+// newTargets creates the batch inserter and sinks of each target.  The records
+// written to the sinks are reported to the tracker.
+func newTargets(configs []TargetConfig, options sinkOptions, timeTracker batchInserter.TimeTracker, tracker *insertTracker) (targets, error) {
+	var ts targets
+	for _, c := range configs {
+		sinkConfig := c.Sink
+		sinkConfig.Name = c.Name
+		primary, err := newSink(sinkConfig, options)
+		if err != nil {
+			return nil, emperror.WrapWith(err, "failed to create target sink", "target", c.Name)
+		}
+		inserter, closeSecondaries, err := newFanout(c.Name, primary, c.Sinks, options)
+		if err != nil {
+			return nil, emperror.WrapWith(err, "failed to create target sinks", "target", c.Name)
+		}
+		b, err := batchInserter.NewBatchInserter(c.BatchInserter, options.logger, options.metricsRegistry, tracker.wrap(inserter), timeTracker)
+		if err != nil {
+			return nil, emperror.WrapWith(err, "failed to create target batch inserter", "target", c.Name)
+		}
+		ts = append(ts, &target{
+			name:          c.Name,
+			batchInserter: b,
+			closeSinks: func() error {
+				err := closeSecondaries()
+				if c, ok := primary.(io.Closer); ok {
+					if closeErr := c.Close(); closeErr != nil && err == nil {
+						err = closeErr
+					}
+				}
+				return err
+			},
+		})
+	}
+	return ts, nil
+}
+
+func (ts targets) Start() {
+	for _, t := range ts {
+		t.batchInserter.Start()
+	}
+}
+
+// Stop waits for each target's records to be written, then closes its sinks.
+func (ts targets) Stop() error {
+	var err error
+	for _, t := range ts {
+		t.batchInserter.Stop()
+		if closeErr := t.closeSinks(); closeErr != nil && err == nil {
+			err = emperror.WrapWith(closeErr, "failed to close target sinks", "target", t.name)
+		}
+	}
+	return err
+}
+
+// targetRouter inserts records into the target named by their rule, or into
+// the database if their rule doesn't name one.  It tracks the records until
+// they're written, wherever they're stored.
+type targetRouter struct {
+	inserter recordInserter
+	targets  map[string]recordInserter
+	tracker  *insertTracker
+}
+
+func newTargetRouter(inserter recordInserter, ts targets, tracker *insertTracker) *targetRouter {
+	t := &targetRouter{
+		inserter: inserter,
+		targets:  make(map[string]recordInserter, len(ts)),
+		tracker:  tracker,
+	}
+	for _, target := range ts {
+		t.targets[target.name] = target.batchInserter
+	}
+	return t
+}
+
+func (t *targetRouter) Track(record db.Record, done func(error)) func() {
+	return t.tracker.Track(record, done)
+}
+
+func (t *targetRouter) Insert(record batchInserter.RecordWithTime) error {
+	return t.inserter.Insert(record)
+}
+
+func (t *targetRouter) InsertToTarget(record batchInserter.RecordWithTime, target string, priority rules.Priority) error {
+	if target == "" {
+		if pi, ok := t.inserter.(requestParser.PriorityInserter); ok {
+			return pi.InsertWithPriority(record, priority)
+		}
+		return t.inserter.Insert(record)
+	}
+	i, ok := t.targets[target]
+	if !ok {
+		return emperror.With(errUnknownTarget, "target", target)
+	}
+	return i.Insert(record)
+}
+
+// validateTargets checks that the targets have unique names, that every rule
+// names a configured target, and that the sink names used in metrics are
+// unique.
+func validateTargets(config *SvalinnConfig) error {
+	names := make(map[string]bool)
+	for _, t := range config.Targets {
+		switch {
+		case t.Name == "":
+			return errNoTargetName
+		case names[t.Name]:
+			return emperror.With(errDuplicateTarget, "target", t.Name)
+		}
+		names[t.Name] = true
+	}
+
+	ruleConfigs := append([]rules.RuleConfig(nil), config.RequestParser.RegexRules...)
+	for _, r := range config.Webhook.Registrations {
+		ruleConfigs = append(ruleConfigs, r.RegexRules...)
+	}
+	for _, r := range ruleConfigs {
+		if r.Target != "" && !names[r.Target] {
+			return emperror.With(errUnknownTarget, "regex", r.Regex, "target", r.Target)
+		}
+	}
+
+	sinkNames := map[string]bool{sink.PrimaryName: true}
+	addSinks := func(configs []SinkConfig) error {
+		for _, s := range configs {
+			if sinkNames[s.Name] {
+				return emperror.With(errDuplicateSinkName, "name", s.Name)
+			}
+			sinkNames[s.Name] = true
+		}
+		return nil
+	}
+	if err := addSinks(config.Sinks); err != nil {
+		return err
+	}
+	for _, t := range config.Targets {
+		if err := addSinks(append([]SinkConfig{{Name: t.Name}}, t.Sinks...)); err != nil {
+			return emperror.WrapWith(err, "invalid target sinks", "target", t.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/codex-db"
+	"github.com/xmidt-org/codex-db/batchInserter"
+	"github.com/xmidt-org/svalinn/requestParser"
+	"github.com/xmidt-org/svalinn/rules"
+	"github.com/xmidt-org/svalinn/sink"
+	"github.com/xmidt-org/webpa-common/v2/logging"
+	"github.com/xmidt-org/webpa-common/v2/xmetrics"
+)
+
+func TestTargetRouter(t *testing.T) {
+	record := batchInserter.RecordWithTime{Record: db.Record{DeviceID: "mac:112233445566"}}
+
+	t.Run("Default", func(t *testing.T) {
+		normal, high, bulk := new(mockInserter), new(mockInserter), new(mockInserter)
+		high.On("Insert", record).Return(nil).Once()
+		normal.On("Insert", record).Return(nil).Once()
+		router := &targetRouter{
+			inserter: &priorityInserter{inserter: normal, highPriority: high},
+			targets:  map[string]recordInserter{"bulk": bulk},
+		}
+		assert.Nil(t, router.InsertToTarget(record, "", rules.HighPriority))
+		assert.Nil(t, router.Insert(record))
+		normal.AssertExpectations(t)
+		high.AssertExpectations(t)
+		bulk.AssertExpectations(t)
+	})
+
+	t.Run("Target", func(t *testing.T) {
+		normal, bulk := new(mockInserter), new(mockInserter)
+		insertErr := errors.New("test insert error")
+		bulk.On("Insert", record).Return(insertErr).Once()
+		router := &targetRouter{
+			inserter: normal,
+			targets:  map[string]recordInserter{"bulk": bulk},
+		}
+		assert.Equal(t, insertErr, router.InsertToTarget(record, "bulk", rules.HighPriority))
+		normal.AssertExpectations(t)
+		bulk.AssertExpectations(t)
+	})
+
+	t.Run("Unknown Target", func(t *testing.T) {
+		normal := new(mockInserter)
+		router := newTargetRouter(normal, nil, newInsertTracker())
+		err := router.InsertToTarget(record, "tape", rules.NormalPriority)
+		require.NotNil(t, err)
+		assert.Contains(t, err.Error(), errUnknownTarget.Error())
+		normal.AssertNotCalled(t, "Insert", mock.Anything)
+	})
+}
+
+func TestNewTargets(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	primary, secondary := new(testSink), new(testSink)
+	sinkFactories["test"] = func(config SinkConfig, _ sinkOptions) (db.Inserter, error) {
+		if config.Name == "bulk" {
+			return primary, nil
+		}
+		return secondary, nil
+	}
+	defer delete(sinkFactories, "test")
+	registry, err := xmetrics.NewRegistry(nil, Metrics, batchInserter.Metrics, sink.Metrics)
+	require.Nil(err)
+	options := sinkOptions{metricsRegistry: registry, logger: logging.NewTestLogger(nil, t)}
+
+	tracker := newInsertTracker()
+	ts, err := newTargets([]TargetConfig{
+		{
+			Name:  "bulk",
+			Sink:  SinkConfig{Type: "test"},
+			Sinks: []SinkConfig{{Name: "bulk-archive", Type: "test"}},
+		},
+	}, options, NewMeasures(registry), tracker)
+	require.Nil(err)
+	require.Len(ts, 1)
+
+	ts.Start()
+	router := newTargetRouter(new(mockInserter), ts, tracker)
+	record := batchInserter.RecordWithTime{
+		Record:    db.Record{DeviceID: "mac:112233445566", Data: []byte("data")},
+		Beginning: time.Now(),
+	}
+	var doneErrs []error
+	router.Track(record.Record, func(err error) {
+		doneErrs = append(doneErrs, err)
+	})
+	assert.Nil(router.InsertToTarget(record, "bulk", rules.NormalPriority))
+	assert.Nil(ts.Stop())
+	assert.Len(primary.records, 1)
+	assert.Len(secondary.records, 1)
+	// the record was tracked until the target's batch was written.
+	assert.Equal([]error{nil}, doneErrs)
+
+	_, err = newTargets([]TargetConfig{{Name: "bulk", Sink: SinkConfig{Type: "tape"}}}, options, NewMeasures(registry), newInsertTracker())
+	require.NotNil(err)
+	assert.Contains(err.Error(), errUnknownSinkType.Error())
+}
+
+func TestValidateTargets(t *testing.T) {
+	tests := []struct {
+		description string
+		config      SvalinnConfig
+		expectedErr error
+	}{
+		{
+			description: "No Targets",
+		},
+		{
+			description: "Success",
+			config: SvalinnConfig{
+				Sinks:   []SinkConfig{{Name: "archive"}},
+				Targets: []TargetConfig{{Name: "bulk", Sinks: []SinkConfig{{Name: "bulk-archive"}}}},
+				RequestParser: requestParser.Config{RegexRules: []rules.RuleConfig{
+					{Regex: ".*/online$", Target: "bulk"},
+				}},
+			},
+		},
+		{
+			description: "No Name",
+			config:      SvalinnConfig{Targets: []TargetConfig{{}}},
+			expectedErr: errNoTargetName,
+		},
+		{
+			description: "Duplicate Target",
+			config:      SvalinnConfig{Targets: []TargetConfig{{Name: "bulk"}, {Name: "bulk"}}},
+			expectedErr: errDuplicateTarget,
+		},
+		{
+			description: "Unknown Target",
+			config: SvalinnConfig{
+				Webhook: WebhookConfig{Registrations: []RegistrationConfig{
+					{RegexRules: []rules.RuleConfig{{Regex: ".*", Target: "bulk"}}},
+				}},
+			},
+			expectedErr: errUnknownTarget,
+		},
+		{
+			description: "Target Named Like A Sink",
+			config: SvalinnConfig{
+				Sinks:   []SinkConfig{{Name: "bulk"}},
+				Targets: []TargetConfig{{Name: "bulk"}},
+			},
+			expectedErr: errDuplicateSinkName,
+		},
+		{
+			description: "Sink Named Primary",
+			config:      SvalinnConfig{Sinks: []SinkConfig{{Name: sink.PrimaryName}}},
+			expectedErr: errDuplicateSinkName,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			config := tc.config
+			err := validateTargets(&config)
+			if tc.expectedErr == nil {
+				assert.Nil(t, err)
+				return
+			}
+			require.NotNil(t, err)
+			assert.Contains(t, err.Error(), tc.expectedErr.Error())
+		})
+	}
+}
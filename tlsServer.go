@@ -0,0 +1,271 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/goph/emperror"
+	"github.com/xmidt-org/webpa-common/v2/logging"
+)
+
+const (
+	defaultReloadInterval = time.Minute
+)
+
+var (
+	errNoCertificate      = errors.New("certificateFile and keyFile are required for TLS")
+	errNoClientCerts      = errors.New("no client CA certificates found")
+	errSubjectNotAllowed  = errors.New("client certificate subject isn't allowed")
+	errNoClientCertChains = errors.New("no verified client certificate")
+)
+
+// TLSConfig sets up serving the webhook endpoint over TLS.  Setting the
+// ClientCACertFile requires clients to present a certificate signed by one of
+// the CAs in it.
+type TLSConfig struct {
+	// Address is where the TLS server listens.  If empty, the webhook
+	// endpoint is served by the primary server.
+	Address          string
+	CertificateFile  string
+	KeyFile          string
+	ClientCACertFile string
+
+	// AllowedSubjects limits the client certificates accepted to those with
+	// a common name or full subject in the list.  If empty, any certificate
+	// signed by the client CAs is accepted.
+	AllowedSubjects []string
+
+	// ReloadInterval is how often the files are checked for changes.
+	ReloadInterval time.Duration
+
+	MinVersion uint16
+}
+
+// certReloader keeps the server certificate and client CAs loaded from disk,
+// loading them again when the files change.
+type certReloader struct {
+	config   TLSConfig
+	logger   log.Logger
+	lock     sync.RWMutex
+	cert     *tls.Certificate
+	clientCA *x509.CertPool
+	modTimes map[string]time.Time
+}
+
+func newCertReloader(config TLSConfig, logger log.Logger) (*certReloader, error) {
+	if config.CertificateFile == "" || config.KeyFile == "" {
+		return nil, errNoCertificate
+	}
+	c := &certReloader{
+		config:   config,
+		logger:   logger,
+		modTimes: make(map[string]time.Time),
+	}
+	if _, err := c.reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *certReloader) files() []string {
+	files := []string{c.config.CertificateFile, c.config.KeyFile}
+	if c.config.ClientCACertFile != "" {
+		files = append(files, c.config.ClientCACertFile)
+	}
+	return files
+}
+
+// reload loads the files again if any have changed since they were last
+// loaded.  If loading fails, the previous certificates are kept.
+func (c *certReloader) reload() (bool, error) {
+	modTimes := make(map[string]time.Time, 3)
+	changed := false
+	for _, f := range c.files() {
+		info, err := os.Stat(f)
+		if err != nil {
+			return false, emperror.WrapWith(err, "failed to check file", "file", f)
+		}
+		modTimes[f] = info.ModTime()
+		if !info.ModTime().Equal(c.modTimes[f]) {
+			changed = true
+		}
+	}
+	if !changed {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.config.CertificateFile, c.config.KeyFile)
+	if err != nil {
+		return false, emperror.Wrap(err, "failed to load certificate")
+	}
+	var clientCA *x509.CertPool
+	if c.config.ClientCACertFile != "" {
+		pem, err := ioutil.ReadFile(c.config.ClientCACertFile)
+		if err != nil {
+			return false, emperror.Wrap(err, "failed to read client CA certificates")
+		}
+		clientCA = x509.NewCertPool()
+		if !clientCA.AppendCertsFromPEM(pem) {
+			return false, errNoClientCerts
+		}
+	}
+
+	c.lock.Lock()
+	c.cert, c.clientCA, c.modTimes = &cert, clientCA, modTimes
+	c.lock.Unlock()
+	return true, nil
+}
+
+// watch reloads the files every interval until stop is closed.
+func (c *certReloader) watch(stop <-chan struct{}) {
+	interval := c.config.ReloadInterval
+	if interval <= 0 {
+		interval = defaultReloadInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			reloaded, err := c.reload()
+			if err != nil {
+				logging.Error(c.logger, emperror.Context(err)...).Log(logging.MessageKey(), "Failed to reload TLS certificates, keeping the current ones",
+					logging.ErrorKey(), err.Error())
+				continue
+			}
+			if reloaded {
+				logging.Info(c.logger).Log(logging.MessageKey(), "Reloaded TLS certificates")
+			}
+		}
+	}
+}
+
+func (c *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.cert, nil
+}
+
+// tlsConfig builds the config for each connection, so new connections always
+// use the latest certificates.
+func (c *certReloader) tlsConfig() *tls.Config {
+	base := &tls.Config{
+		MinVersion:     c.config.MinVersion,
+		GetCertificate: c.getCertificate,
+	}
+	if base.MinVersion == 0 {
+		base.MinVersion = tls.VersionTLS12
+	}
+	if c.config.ClientCACertFile == "" {
+		return base
+	}
+
+	allowed := make(map[string]bool, len(c.config.AllowedSubjects))
+	for _, s := range c.config.AllowedSubjects {
+		allowed[s] = true
+	}
+	base.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		c.lock.RLock()
+		clientCA := c.clientCA
+		c.lock.RUnlock()
+		config := base.Clone()
+		config.GetConfigForClient = nil
+		config.ClientCAs = clientCA
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+		if len(allowed) > 0 {
+			config.VerifyPeerCertificate = verifySubject(allowed)
+		}
+		return config, nil
+	}
+	return base
+}
+
+// verifySubject only accepts client certificates with a common name or full
+// subject that is allowed.
+func verifySubject(allowed map[string]bool) func([][]byte, [][]*x509.Certificate) error {
+	return func(_ [][]byte, verifiedChains [][]*x509.Certificate) error {
+		if len(verifiedChains) == 0 || len(verifiedChains[0]) == 0 {
+			return errNoClientCertChains
+		}
+		subject := verifiedChains[0][0].Subject
+		if allowed[subject.CommonName] || allowed[subject.String()] {
+			return nil
+		}
+		return emperror.With(errSubjectNotAllowed, "subject", subject.String())
+	}
+}
+
+// tlsServer serves a handler over TLS, reloading its certificates as they
+// change.
+type tlsServer struct {
+	server   *http.Server
+	listener net.Listener
+	reloader *certReloader
+	stop     chan struct{}
+	logger   log.Logger
+}
+
+// newTLSServer sets up the server given to serve over TLS at the configured
+// address.
+func newTLSServer(config TLSConfig, server *http.Server, logger log.Logger) (*tlsServer, error) {
+	reloader, err := newCertReloader(config, logger)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := reloader.tlsConfig()
+	listener, err := net.Listen("tcp", config.Address)
+	if err != nil {
+		return nil, emperror.WrapWith(err, "failed to listen", "address", config.Address)
+	}
+	server.TLSConfig = tlsConfig
+	return &tlsServer{
+		server:   server,
+		listener: tls.NewListener(listener, tlsConfig),
+		reloader: reloader,
+		stop:     make(chan struct{}),
+		logger:   logger,
+	}, nil
+}
+
+func (t *tlsServer) Start() {
+	go t.reloader.watch(t.stop)
+	go func() {
+		err := t.server.Serve(t.listener)
+		if err != nil && err != http.ErrServerClosed {
+			logging.Error(t.logger).Log(logging.MessageKey(), "TLS server exited", logging.ErrorKey(), err.Error())
+		}
+	}()
+}
+
+func (t *tlsServer) Stop(ctx context.Context) error {
+	close(t.stop)
+	return t.server.Shutdown(ctx)
+}
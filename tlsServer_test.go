@@ -0,0 +1,233 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/v2/logging"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCert(t *testing.T, cn string, serial int64, parent *testCert) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn, Organization: []string{"test"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     []string{"localhost"},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	require.Nil(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.Nil(t, err)
+	return &testCert{
+		cert: cert,
+		key:  key,
+		pem:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+func (c *testCert) write(t *testing.T, certFile, keyFile string) {
+	require.Nil(t, ioutil.WriteFile(certFile, c.pem, 0600))
+	der, err := x509.MarshalECPrivateKey(c.key)
+	require.Nil(t, err)
+	require.Nil(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600))
+}
+
+func (c *testCert) tlsCert() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.cert.Raw}, PrivateKey: c.key}
+}
+
+func TestTLSServer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	ca := newTestCert(t, "test ca", 1, nil)
+	serverCert := newTestCert(t, "localhost", 2, ca)
+	allowedClient := newTestCert(t, "allowed-client", 3, ca)
+	otherClient := newTestCert(t, "other-client", 4, ca)
+	untrustedClient := newTestCert(t, "allowed-client", 5, nil)
+
+	certFile, keyFile, caFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"), filepath.Join(dir, "ca.pem")
+	serverCert.write(t, certFile, keyFile)
+	require.Nil(t, ioutil.WriteFile(caFile, ca.pem, 0600))
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	tests := []struct {
+		description     string
+		clientCAs       bool
+		allowedSubjects []string
+		clientCert      *testCert
+		expectedErr     bool
+	}{
+		{
+			description: "TLS Success",
+		},
+		{
+			description: "mTLS Success",
+			clientCAs:   true,
+			clientCert:  allowedClient,
+		},
+		{
+			description:     "mTLS Allowed Subject",
+			clientCAs:       true,
+			allowedSubjects: []string{"allowed-client"},
+			clientCert:      allowedClient,
+		},
+		{
+			description:     "mTLS Subject Not Allowed",
+			clientCAs:       true,
+			allowedSubjects: []string{"allowed-client"},
+			clientCert:      otherClient,
+			expectedErr:     true,
+		},
+		{
+			description:     "mTLS Untrusted Client",
+			clientCAs:       true,
+			allowedSubjects: []string{"allowed-client"},
+			clientCert:      untrustedClient,
+			expectedErr:     true,
+		},
+		{
+			description: "mTLS No Client Certificate",
+			clientCAs:   true,
+			expectedErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			config := TLSConfig{
+				Address:         "127.0.0.1:0",
+				CertificateFile: certFile,
+				KeyFile:         keyFile,
+				AllowedSubjects: tc.allowedSubjects,
+			}
+			if tc.clientCAs {
+				config.ClientCACertFile = caFile
+			}
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusAccepted)
+			})
+			server, err := newTLSServer(config, &http.Server{Handler: handler}, logging.NewTestLogger(nil, t))
+			require.Nil(t, err)
+			server.Start()
+			defer server.Stop(context.Background())
+
+			clientConfig := &tls.Config{RootCAs: roots, ServerName: "localhost"}
+			if tc.clientCert != nil {
+				clientConfig.Certificates = []tls.Certificate{tc.clientCert.tlsCert()}
+			}
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientConfig}}
+			resp, err := client.Get("https://" + server.listener.Addr().String())
+			if tc.expectedErr {
+				assert.NotNil(err)
+				return
+			}
+			require.Nil(t, err)
+			resp.Body.Close()
+			assert.Equal(http.StatusAccepted, resp.StatusCode)
+		})
+	}
+}
+
+func TestCertReloader(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	dir, err := ioutil.TempDir("", "tls")
+	require.Nil(err)
+	defer os.RemoveAll(dir)
+
+	ca := newTestCert(t, "test ca", 1, nil)
+	first := newTestCert(t, "localhost", 2, ca)
+	second := newTestCert(t, "localhost", 3, ca)
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	first.write(t, certFile, keyFile)
+
+	_, err = newCertReloader(TLSConfig{CertificateFile: certFile}, logging.NewTestLogger(nil, t))
+	assert.Equal(errNoCertificate, err)
+
+	reloader, err := newCertReloader(TLSConfig{CertificateFile: certFile, KeyFile: keyFile}, logging.NewTestLogger(nil, t))
+	require.Nil(err)
+	cert, err := reloader.getCertificate(nil)
+	require.Nil(err)
+	assert.Equal(first.cert.Raw, cert.Certificate[0])
+
+	// nothing changed, so nothing is reloaded.
+	reloaded, err := reloader.reload()
+	assert.Nil(err)
+	assert.False(reloaded)
+
+	// a bad certificate keeps the current one.
+	require.Nil(ioutil.WriteFile(certFile, []byte("bad"), 0600))
+	future := time.Now().Add(time.Minute)
+	require.Nil(os.Chtimes(certFile, future, future))
+	reloaded, err = reloader.reload()
+	assert.NotNil(err)
+	assert.False(reloaded)
+	cert, err = reloader.getCertificate(nil)
+	require.Nil(err)
+	assert.Equal(first.cert.Raw, cert.Certificate[0])
+
+	second.write(t, certFile, keyFile)
+	future = future.Add(time.Minute)
+	require.Nil(os.Chtimes(certFile, future, future))
+	require.Nil(os.Chtimes(keyFile, future, future))
+	reloaded, err = reloader.reload()
+	assert.Nil(err)
+	assert.True(reloaded)
+	cert, err = reloader.getCertificate(nil)
+	require.Nil(err)
+	assert.Equal(second.cert.Raw, cert.Certificate[0])
+}
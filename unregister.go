@@ -0,0 +1,182 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/goph/emperror"
+	webhook "github.com/xmidt-org/wrp-listener"
+	"github.com/xmidt-org/wrp-listener/webhookClient"
+)
+
+const (
+	unregisterNone   = "none"
+	unregisterDelete = "delete"
+	unregisterExpire = "expire"
+
+	defaultUnregisterTTL     = 5 * time.Second
+	defaultUnregisterTimeout = 5 * time.Second
+
+	instanceQueryParam = "instance"
+)
+
+var (
+	errUnknownUnregisterMode = errors.New("unknown unregister mode")
+	errUnregisterFailed      = errors.New("failed to unregister webhook")
+)
+
+// UnregisterConfig sets up removing the webhook registrations when Svalinn
+// shuts down, so events stop being sent to an instance that's gone.
+type UnregisterConfig struct {
+	// Mode is none, delete, or expire.  Delete sends a DELETE request with the
+	// registration to the registration URL.  Expire registers again with a
+	// short TTL, for webhook services that can't delete registrations.
+	Mode string
+
+	// TTL is the duration of the registration sent with the expire mode.
+	TTL time.Duration
+
+	// Timeout is how long to wait for the unregistering request.
+	Timeout time.Duration
+}
+
+// unregisterer removes a webhook registration.
+type unregisterer interface {
+	Unregister() error
+}
+
+// newUnregisterer creates the unregisterer for the mode, or returns nil if
+// registrations aren't removed.
+func newUnregisterer(config UnregisterConfig, acquirer webhookClient.Acquirer, secret webhookClient.SecretGetter,
+	basicConfig webhookClient.BasicConfig) (unregisterer, error) {
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = defaultUnregisterTimeout
+	}
+	switch strings.ToLower(config.Mode) {
+	case "", unregisterNone:
+		return nil, nil
+	case unregisterDelete:
+		return &deleteUnregisterer{
+			acquirer:        acquirer,
+			secret:          secret,
+			registrationURL: basicConfig.RegistrationURL,
+			request:         basicConfig.Request,
+			client:          &http.Client{Timeout: timeout},
+		}, nil
+	case unregisterExpire:
+		ttl := config.TTL
+		if ttl <= 0 {
+			ttl = defaultUnregisterTTL
+		}
+		basicConfig.Timeout = timeout
+		basicConfig.Request.Duration = ttl
+		basicConfig.Request.Until = time.Time{}
+		registerer, err := webhookClient.NewBasicRegisterer(acquirer, secret, basicConfig)
+		if err != nil {
+			return nil, err
+		}
+		return expireUnregisterer{registerer: registerer}, nil
+	}
+	return nil, emperror.With(errUnknownUnregisterMode, "mode", config.Mode)
+}
+
+// expireUnregisterer registers again with a short TTL, so the registration
+// expires soon after Svalinn shuts down.
+type expireUnregisterer struct {
+	registerer webhookClient.Registerer
+}
+
+func (e expireUnregisterer) Unregister() error {
+	return e.registerer.Register()
+}
+
+// deleteUnregisterer asks the webhook service to delete the registration.
+type deleteUnregisterer struct {
+	acquirer        webhookClient.Acquirer
+	secret          webhookClient.SecretGetter
+	registrationURL string
+	request         webhook.W
+	client          *http.Client
+}
+
+func (d *deleteUnregisterer) Unregister() error {
+	secret, err := d.secret.GetSecret()
+	if err != nil {
+		return emperror.Wrap(err, "failed to get secret")
+	}
+	request := d.request
+	request.Config.Secret = secret
+	body, err := json.Marshal(&request)
+	if err != nil {
+		return emperror.Wrap(err, "failed to marshal request")
+	}
+	token, err := d.acquirer.Acquire()
+	if err != nil {
+		return emperror.Wrap(err, "failed to acquire token")
+	}
+	req, err := http.NewRequest(http.MethodDelete, d.registrationURL, bytes.NewReader(body))
+	if err != nil {
+		return emperror.Wrap(err, "failed to create request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return emperror.Wrap(err, "failed to send request")
+	}
+	defer resp.Body.Close()
+	// read the body so the connection can be reused.
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return emperror.With(errUnregisterFailed, "code", resp.StatusCode)
+	}
+	return nil
+}
+
+// instanceURL adds this instance's id to a callback url, so each instance
+// has its own registration.  The id defaults to the hostname.
+func instanceURL(callback string, instanceID string) (string, error) {
+	if instanceID == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return "", emperror.Wrap(err, "failed to get hostname for instance id")
+		}
+		instanceID = hostname
+	}
+	u, err := url.Parse(callback)
+	if err != nil {
+		return "", emperror.WrapWith(err, "failed to parse callback url", "url", callback)
+	}
+	query := u.Query()
+	query.Set(instanceQueryParam, instanceID)
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
@@ -0,0 +1,148 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/bascule/acquire"
+	webhook "github.com/xmidt-org/wrp-listener"
+	secretGetter "github.com/xmidt-org/wrp-listener/secret"
+	"github.com/xmidt-org/wrp-listener/webhookClient"
+)
+
+func TestNewUnregisterer(t *testing.T) {
+	const callback = "http://svalinn.example.com/api/v1/device-status"
+	tests := []struct {
+		description      string
+		config           UnregisterConfig
+		responseCode     int
+		expectNil        bool
+		expectedMethod   string
+		expectedDuration time.Duration
+		expectedErr      error
+		expectedUnregErr error
+	}{
+		{
+			description: "None",
+			expectNil:   true,
+		},
+		{
+			description:    "Delete",
+			config:         UnregisterConfig{Mode: "delete"},
+			responseCode:   http.StatusOK,
+			expectedMethod: http.MethodDelete,
+		},
+		{
+			description:      "Delete Rejected",
+			config:           UnregisterConfig{Mode: "delete"},
+			responseCode:     http.StatusNotFound,
+			expectedMethod:   http.MethodDelete,
+			expectedUnregErr: errUnregisterFailed,
+		},
+		{
+			description:      "Expire",
+			config:           UnregisterConfig{Mode: "Expire", TTL: time.Second},
+			responseCode:     http.StatusOK,
+			expectedMethod:   http.MethodPost,
+			expectedDuration: time.Second,
+		},
+		{
+			description:      "Expire Default TTL",
+			config:           UnregisterConfig{Mode: "expire"},
+			responseCode:     http.StatusOK,
+			expectedMethod:   http.MethodPost,
+			expectedDuration: defaultUnregisterTTL,
+		},
+		{
+			description: "Unknown Mode",
+			config:      UnregisterConfig{Mode: "forget"},
+			expectedErr: errUnknownUnregisterMode,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			var (
+				method, auth string
+				received     webhook.W
+			)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				method, auth = r.Method, r.Header.Get("Authorization")
+				assert.Nil(json.NewDecoder(r.Body).Decode(&received))
+				w.WriteHeader(tc.responseCode)
+			}))
+			defer server.Close()
+
+			acquirer, err := acquire.NewFixedAuthAcquirer("Basic abc")
+			require.Nil(err)
+			u, err := newUnregisterer(tc.config, acquirer, secretGetter.NewConstantSecret("secret"), webhookClient.BasicConfig{
+				RegistrationURL: server.URL,
+				Request: webhook.W{
+					Config:   webhook.Config{URL: callback},
+					Events:   []string{".*"},
+					Duration: time.Hour,
+				},
+			})
+			if tc.expectedErr != nil {
+				require.NotNil(err)
+				assert.Contains(err.Error(), tc.expectedErr.Error())
+				return
+			}
+			require.Nil(err)
+			if tc.expectNil {
+				assert.Nil(u)
+				return
+			}
+			require.NotNil(u)
+
+			err = u.Unregister()
+			if tc.expectedUnregErr != nil {
+				require.NotNil(err)
+				assert.Contains(err.Error(), tc.expectedUnregErr.Error())
+			} else {
+				assert.Nil(err)
+			}
+			assert.Equal(tc.expectedMethod, method)
+			assert.Equal("Basic abc", auth)
+			assert.Equal(callback, received.Config.URL)
+			assert.Equal("secret", received.Config.Secret)
+			if tc.expectedDuration > 0 {
+				assert.Equal(tc.expectedDuration, received.Duration)
+			}
+		})
+	}
+}
+
+func TestInstanceURL(t *testing.T) {
+	assert := assert.New(t)
+	u, err := instanceURL("http://svalinn.example.com/api/v1/device-status", "pod-1")
+	assert.Nil(err)
+	assert.Equal("http://svalinn.example.com/api/v1/device-status?instance=pod-1", u)
+
+	u, err = instanceURL("http://svalinn.example.com/api/v1/device-status?a=b", "pod 2")
+	assert.Nil(err)
+	assert.Equal("http://svalinn.example.com/api/v1/device-status?a=b&instance=pod+2", u)
+}
@@ -0,0 +1,165 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"errors"
+
+	"github.com/goph/emperror"
+	"github.com/xmidt-org/svalinn/rules"
+	webhook "github.com/xmidt-org/wrp-listener"
+)
+
+const (
+	defaultRegistrationName = "default"
+)
+
+var (
+	errNoRegistrationName     = errors.New("registration has no name")
+	errDuplicateRegistration  = errors.New("registration name is used more than once")
+	errNoRegistrationEndpoint = errors.New("registration has no endpoint")
+	errDuplicateEndpoint      = errors.New("endpoint is used by more than one registration")
+	errNoRegistrationEvents   = errors.New("registration has no events")
+)
+
+// RegistrationConfig is a webhook registered in addition to the one described
+// by the endpoint and webhook.request.  Each registration has its own
+// endpoint, so events can be authenticated with its secret and parsed with
+// its rules.
+type RegistrationConfig struct {
+	// Name identifies the registration in logs and health checks.
+	Name string
+
+	// Endpoint is the path the events are sent to, after the api base.
+	Endpoint string
+
+	// Request is sent when registering.  The request's config url is the
+	// server the events are sent to, which defaults to this server.
+	Request webhook.W
+
+	// RegexRules replace the request parser's rules for events sent to this
+	// endpoint.  If empty, the request parser's rules are used.  With
+	// webhook.deriveEvents, the events registered come from these rules.
+	RegexRules []rules.RuleConfig
+
+	// Rotation accepts the registration's previous secret while its sender
+	// switches to the new one.  It doesn't apply to any other registration.
+	Rotation SecretRotationConfig
+}
+
+// registration is a webhook to register and the endpoint that receives its
+// events.
+type registration struct {
+	name     string
+	endpoint string
+	request  webhook.W
+	rules    rules.Rules
+
+	// rotation is how the registration's secret is being rotated, which is
+	// secret.rotation for the default registration.
+	rotation SecretRotationConfig
+
+	// ruleConfigs are the rules the endpoint's events are parsed with, which
+	// are the request parser's unless the registration has its own.
+	ruleConfigs []rules.RuleConfig
+}
+
+// secret is the secret the sender signs this registration's events with.
+func (r registration) secret() string {
+	return r.request.Config.Secret
+}
+
+// shouldRegister is whether there is enough to register the webhook.  The
+// endpoint is served either way.
+func (r registration) shouldRegister() bool {
+	return r.request.Config.URL != "" && len(r.request.Events) > 0
+}
+
+// unreachableRules finds the rules that can never receive events given the
+// events registered.
+func (r registration) unreachableRules() ([]rules.RuleConfig, error) {
+	return unreachableRules(r.ruleConfigs, r.request.Events)
+}
+
+// newRegistrations gets the default registration from the endpoint and
+// webhook.request along with the rest of the configured registrations.  The
+// request urls are completed with the path of each endpoint and, if
+// configured, the instance id.  If webhook.deriveEvents is set, the events
+// registered are derived from each registration's rules.
+func newRegistrations(config *SvalinnConfig, server string) ([]registration, error) {
+	registrations := []registration{{
+		name:        defaultRegistrationName,
+		endpoint:    config.Endpoint,
+		request:     config.Webhook.Request,
+		rotation:    config.Secret.Rotation,
+		ruleConfigs: config.RequestParser.RegexRules,
+	}}
+	names := map[string]bool{defaultRegistrationName: true}
+	endpoints := map[string]bool{config.Endpoint: true}
+	for _, c := range config.Webhook.Registrations {
+		switch {
+		case c.Name == "":
+			return nil, emperror.With(errNoRegistrationName, "endpoint", c.Endpoint)
+		case names[c.Name]:
+			return nil, emperror.With(errDuplicateRegistration, "name", c.Name)
+		case c.Endpoint == "":
+			return nil, emperror.With(errNoRegistrationEndpoint, "name", c.Name)
+		case endpoints[c.Endpoint]:
+			return nil, emperror.With(errDuplicateEndpoint, "name", c.Name, "endpoint", c.Endpoint)
+		case len(c.Request.Events) == 0 && !config.Webhook.DeriveEvents:
+			return nil, emperror.With(errNoRegistrationEvents, "name", c.Name)
+		}
+		names[c.Name], endpoints[c.Endpoint] = true, true
+
+		r, err := rules.NewRules(c.RegexRules)
+		if err != nil {
+			return nil, emperror.WrapWith(err, "failed to create rules", "name", c.Name)
+		}
+		ruleConfigs := c.RegexRules
+		if len(ruleConfigs) == 0 {
+			ruleConfigs = config.RequestParser.RegexRules
+		}
+		registrations = append(registrations, registration{
+			name:        c.Name,
+			endpoint:    c.Endpoint,
+			request:     c.Request,
+			rules:       r,
+			rotation:    c.Rotation,
+			ruleConfigs: ruleConfigs,
+		})
+	}
+
+	for i := range registrations {
+		r := &registrations[i]
+		if config.Webhook.DeriveEvents {
+			r.request.Events = deriveEvents(r.ruleConfigs)
+		}
+		if r.request.Config.URL == "" {
+			r.request.Config.URL = server
+		}
+		r.request.Config.URL = r.request.Config.URL + apiBase + r.endpoint
+		if config.Webhook.InstanceURL {
+			u, err := instanceURL(r.request.Config.URL, config.Webhook.InstanceID)
+			if err != nil {
+				return nil, emperror.WrapWith(err, "failed to add instance id", "name", r.name)
+			}
+			r.request.Config.URL = u
+		}
+	}
+	return registrations, nil
+}
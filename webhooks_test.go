@@ -0,0 +1,164 @@
+/**
+ * Copyright 2026 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/svalinn/rules"
+	webhook "github.com/xmidt-org/wrp-listener"
+)
+
+func TestNewRegistrations(t *testing.T) {
+	const server = "http://svalinn.example.com:8181"
+	request := func(url string, events ...string) webhook.W {
+		return webhook.W{Config: webhook.Config{URL: url, Secret: "secret"}, Events: events}
+	}
+	tests := []struct {
+		description   string
+		registrations []RegistrationConfig
+		expectedURLs  []string
+		expectedRules []bool
+		expectedErr   error
+	}{
+		{
+			description:   "Default Only",
+			expectedURLs:  []string{server + apiBase + "/device-status"},
+			expectedRules: []bool{false},
+		},
+		{
+			description: "Several Registrations",
+			registrations: []RegistrationConfig{
+				{
+					Name:       "online",
+					Endpoint:   "/online",
+					Request:    request("", "device-status/.*/online"),
+					RegexRules: []rules.RuleConfig{{Regex: ".*", EventType: "state"}},
+				},
+				{
+					Name:     "reboot",
+					Endpoint: "/reboot",
+					Request:  request("http://other.example.com", "reboot-.*"),
+				},
+			},
+			expectedURLs: []string{
+				server + apiBase + "/device-status",
+				server + apiBase + "/online",
+				"http://other.example.com" + apiBase + "/reboot",
+			},
+			expectedRules: []bool{false, true, false},
+		},
+		{
+			description:   "No Name",
+			registrations: []RegistrationConfig{{Endpoint: "/online", Request: request("", ".*")}},
+			expectedErr:   errNoRegistrationName,
+		},
+		{
+			description:   "Duplicate Name",
+			registrations: []RegistrationConfig{{Name: defaultRegistrationName, Endpoint: "/online", Request: request("", ".*")}},
+			expectedErr:   errDuplicateRegistration,
+		},
+		{
+			description:   "No Endpoint",
+			registrations: []RegistrationConfig{{Name: "online", Request: request("", ".*")}},
+			expectedErr:   errNoRegistrationEndpoint,
+		},
+		{
+			description:   "Duplicate Endpoint",
+			registrations: []RegistrationConfig{{Name: "online", Endpoint: "/device-status", Request: request("", ".*")}},
+			expectedErr:   errDuplicateEndpoint,
+		},
+		{
+			description:   "No Events",
+			registrations: []RegistrationConfig{{Name: "online", Endpoint: "/online", Request: request("")}},
+			expectedErr:   errNoRegistrationEvents,
+		},
+		{
+			description: "Bad Rule",
+			registrations: []RegistrationConfig{{
+				Name:       "online",
+				Endpoint:   "/online",
+				Request:    request("", ".*"),
+				RegexRules: []rules.RuleConfig{{Regex: "["}},
+			}},
+			expectedErr: errors.New("failed to create rules"),
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			config := &SvalinnConfig{Endpoint: "/device-status"}
+			config.Webhook.Request = request("", "device-status/.*")
+			config.Webhook.Registrations = tc.registrations
+			registrations, err := newRegistrations(config, server)
+			if tc.expectedErr != nil {
+				require.NotNil(t, err)
+				assert.Contains(err.Error(), tc.expectedErr.Error())
+				return
+			}
+			require.Nil(t, err)
+			require.Len(t, registrations, len(tc.expectedURLs))
+			for i, r := range registrations {
+				assert.Equal(tc.expectedURLs[i], r.request.Config.URL)
+				assert.Equal(tc.expectedRules[i], r.rules != nil)
+				assert.True(r.shouldRegister())
+			}
+		})
+	}
+}
+
+func TestNewRegistrationsInstanceURL(t *testing.T) {
+	config := &SvalinnConfig{Endpoint: "/device-status"}
+	config.Webhook.Request.Events = []string{".*"}
+	config.Webhook.InstanceURL = true
+	config.Webhook.InstanceID = "pod-1"
+	registrations, err := newRegistrations(config, "http://svalinn.example.com")
+	require.Nil(t, err)
+	require.Len(t, registrations, 1)
+	assert.Equal(t, "http://svalinn.example.com"+apiBase+"/device-status?instance=pod-1", registrations[0].request.Config.URL)
+}
+
+func TestNewRegistrationsDeriveEvents(t *testing.T) {
+	config := &SvalinnConfig{Endpoint: "/device-status"}
+	config.Webhook.DeriveEvents = true
+	config.Webhook.Request.Events = []string{"ignored"}
+	config.RequestParser.RegexRules = []rules.RuleConfig{
+		{Regex: ".*/online$"},
+		{Regex: "event:reboot-.*"},
+	}
+	config.Webhook.Registrations = []RegistrationConfig{
+		{
+			Name:       "reboots",
+			Endpoint:   "/reboots",
+			RegexRules: []rules.RuleConfig{{Regex: "^event:reboot-.*"}},
+		},
+		{
+			Name:     "parser",
+			Endpoint: "/parser",
+		},
+	}
+	registrations, err := newRegistrations(config, "http://svalinn.example.com")
+	require.Nil(t, err)
+	require.Len(t, registrations, 3)
+	assert.Equal(t, []string{".*/online$", "reboot-.*"}, registrations[0].request.Events)
+	assert.Equal(t, []string{"^reboot-.*"}, registrations[1].request.Events)
+	assert.Equal(t, []string{".*/online$", "reboot-.*"}, registrations[2].request.Events)
+}